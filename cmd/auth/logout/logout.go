@@ -11,6 +11,7 @@ import (
 	"github.com/fosrl/cli/internal/config"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/sshcerts"
 	"github.com/spf13/cobra"
 )
 
@@ -37,57 +38,69 @@ func logoutMain(cmd *cobra.Command) error {
 
 	apiClient := api.FromContext(cmd.Context())
 
-	// Check if client is running before logout
-	olmClient := olm.NewClient("")
-	if olmClient.IsRunning() {
+	// Check if any client is running before logout
+	var cliClients []*olm.Client
+	for _, inst := range olm.RunningInstances() {
+		olmClient := inst.Client()
 		// Check that the client was started by this CLI by verifying the version
 		status, err := olmClient.GetStatus()
 		if err != nil {
 			logger.Warning("Failed to get client status: %v", err)
 			// Continue with logout even if we can't check version
-		} else if status.Agent == olm.AgentName {
-			// Only prompt and stop if client was started by this CLI
-			// Prompt user to confirm they want to disconnect the client
-			var confirm bool
-			confirmForm := huh.NewForm(
-				huh.NewGroup(
-					huh.NewConfirm().
-						Title("A client is currently running. Logging out will disconnect it.").
-						Description("Do you want to continue?").
-						Value(&confirm),
-				),
-			)
-
-			if err := confirmForm.Run(); err != nil {
-				logger.Error("Error: %v", err)
-				return err
-			}
+			continue
+		}
+		// Only prompt and stop clients started by this CLI
+		if status.Agent == olm.AgentName {
+			cliClients = append(cliClients, olmClient)
+		}
+	}
 
-			if !confirm {
-				err := errors.New("logout cancelled")
-				logger.Info("%v", err)
-				return err
-			}
+	if len(cliClients) > 0 {
+		// Prompt user to confirm they want to disconnect the client
+		title := "A client is currently running. Logging out will disconnect it."
+		if len(cliClients) > 1 {
+			title = "Clients are currently running. Logging out will disconnect them."
+		}
+		var confirm bool
+		confirmForm := huh.NewForm(
+			huh.NewGroup(
+				huh.NewConfirm().
+					Title(title).
+					Description("Do you want to continue?").
+					Value(&confirm),
+			),
+		)
+
+		if err := confirmForm.Run(); err != nil {
+			logger.Error("Error: %v", err)
+			return err
+		}
 
+		if !confirm {
+			err := errors.New("logout cancelled")
+			logger.Info("%v", err)
+			return err
+		}
+
+		for _, olmClient := range cliClients {
 			// Kill the client without showing TUI
 			_, err := olmClient.Exit()
 			if err != nil {
 				logger.Warning("Failed to send exit signal to client: %v", err)
-			} else {
-				// Wait for client to stop (poll until socket is gone)
-				maxWait := 10 * time.Second
-				pollInterval := 200 * time.Millisecond
-				elapsed := time.Duration(0)
-				for olmClient.IsRunning() && elapsed < maxWait {
-					time.Sleep(pollInterval)
-					elapsed += pollInterval
-				}
-				if olmClient.IsRunning() {
-					logger.Warning("Client did not stop within timeout")
-				}
+				continue
+			}
+			// Wait for client to stop (poll until socket is gone)
+			maxWait := 10 * time.Second
+			pollInterval := 200 * time.Millisecond
+			elapsed := time.Duration(0)
+			for olmClient.IsRunning() && elapsed < maxWait {
+				time.Sleep(pollInterval)
+				elapsed += pollInterval
+			}
+			if olmClient.IsRunning() {
+				logger.Warning("Client did not stop within timeout")
 			}
 		}
-		// If version doesn't match, skip client shutdown and continue with logout
 	}
 
 	// Check if there's an active session in the account store.
@@ -116,6 +129,12 @@ func logoutMain(cmd *cobra.Command) error {
 		return err
 	}
 
+	// Cached SSH certificates belong to the session that was just ended
+	userID := account.UserID
+	if _, err := sshcerts.Purge(func(e *sshcerts.Entry) bool { return e.UserID == userID }); err != nil {
+		logger.Debug("Failed to purge cached SSH certificates: %v", err)
+	}
+
 	// Print logout message with account name
 	displayName := account.Email
 	if account.Name != nil && *account.Name != "" {
@@ -21,6 +21,7 @@ func ConfigCmd() *cobra.Command {
 	cmd.AddCommand(configListCmd())
 	cmd.AddCommand(configGetCmd())
 	cmd.AddCommand(configSetCmd())
+	cmd.AddCommand(configProfileCmd())
 
 	return cmd
 }
@@ -94,6 +95,18 @@ Examples:
   pangolin config set up.tunnel_dns true
   pangolin config set up.upstream_dns 10.0.0.53
   pangolin config set up.upstream_dns 10.0.0.53,10.0.0.54
+  sudo pangolin config set hooks.post_up 'mount /mnt/share'
+
+Hooks are shell commands the client runs, as root, when it starts
+(pre_up), when the tunnel connects (post_up), when it stops (pre_down,
+post_down) and when a site changes state (on_peer_change). They receive
+PANGOLIN_EVENT, PANGOLIN_ORG_ID, PANGOLIN_INTERFACE and PANGOLIN_INSTANCE,
+plus PANGOLIN_SITE_ID, PANGOLIN_SITE_NAME, PANGOLIN_PEER_PATH and
+PANGOLIN_PEER_PREVIOUS_PATH for site changes. Their output goes to the
+client log, and each is stopped after hooks.timeout (default 30s). A failing
+pre_up hook stops the client from starting. Hooks are stored in
+` + config.HooksFilePath() + ` rather than the config file, so only root can
+set them, and are ignored unless that file is owned by root.
 `,
 		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -122,6 +135,7 @@ func dumpConfig(cfg *config.Config) error {
 		"log_file":               cfg.LogFile,
 		"disable_update_check":   cfg.DisableUpdateCheck,
 		"disable_companion_mode": cfg.DisableCompanionMode,
+		"secret_backend":         cfg.SecretBackend,
 	}
 
 	up := map[string]any{}
@@ -137,10 +151,35 @@ func dumpConfig(cfg *config.Config) error {
 	if cfg.IsSet("up.prefer_local_routes") {
 		up["prefer_local_routes"] = cfg.GetBool("up.prefer_local_routes")
 	}
+	if cfg.IsSet("up.kill_switch") {
+		up["kill_switch"] = cfg.GetString("up.kill_switch")
+	}
+	if names := cfg.UpProfileNames(); len(names) > 0 {
+		profiles := map[string]any{}
+		for _, name := range names {
+			profiles[name], _ = cfg.UpProfile(name)
+		}
+		up["profiles"] = profiles
+	}
 	if len(up) > 0 {
 		out["up"] = up
 	}
 
+	hooksConfig, err := config.LoadHooks()
+	if err != nil {
+		return err
+	}
+	hooks := map[string]any{}
+	for name, command := range hooksConfig.Commands() {
+		hooks[name] = command
+	}
+	if hooksConfig.Timeout != "" {
+		hooks["timeout"] = hooksConfig.Timeout
+	}
+	if len(hooks) > 0 {
+		out["hooks"] = hooks
+	}
+
 	data, err := json.MarshalIndent(out, "", "  ")
 	if err != nil {
 		return err
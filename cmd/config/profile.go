@@ -0,0 +1,165 @@
+package configcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+func configProfileCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage named connection profiles for pangolin up",
+		Long: `Manage named connection profiles. A profile stores a set of pangolin up
+settings under up.profiles.<name> in the config file; use it with
+'pangolin up --profile <name>'.
+
+Flags passed to pangolin up take precedence over the profile, and the
+profile takes precedence over the up.* config keys.`,
+	}
+
+	cmd.AddCommand(configProfileCreateCmd())
+	cmd.AddCommand(configProfileListCmd())
+	cmd.AddCommand(configProfileShowCmd())
+	cmd.AddCommand(configProfileDeleteCmd())
+
+	return cmd
+}
+
+func configProfileCreateCmd() *cobra.Command {
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:   "create <name> <key=value>...",
+		Short: "Create a profile",
+		Long: `Create a profile from key=value settings. Lists are comma-separated.
+
+Supported keys:
+  ` + strings.Join(config.UpProfileOptionKeys(), "\n  ") + `
+
+The client secret cannot be stored in a profile; use secret_file.
+
+Examples:
+  pangolin config profile create office org=acme tunnel_dns=true upstream_dns=10.0.0.53
+  pangolin config profile create travel mtu=1200 ping_interval=10s prefer_local_routes=false
+`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+			name := args[0]
+
+			if _, exists := cfg.UpProfile(name); exists && !replace {
+				return fmt.Errorf("profile %q already exists; pass --replace to overwrite it", name)
+			}
+
+			values := make(map[string]string, len(args)-1)
+			for _, arg := range args[1:] {
+				key, value, ok := strings.Cut(arg, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("invalid setting %q: expected key=value", arg)
+				}
+				values[key] = value
+			}
+
+			if err := cfg.SetUpProfile(name, values); err != nil {
+				return err
+			}
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+
+			fmt.Printf("Profile %s saved; use it with: pangolin up --profile %s\n", name, name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&replace, "replace", false, "Overwrite an existing profile with the same name")
+
+	return cmd
+}
+
+func configProfileListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List profiles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+
+			names := cfg.UpProfileNames()
+			if len(names) == 0 {
+				fmt.Println("No profiles; create one with: pangolin config profile create <name> <key=value>...")
+				return nil
+			}
+
+			rows := make([][]string, 0, len(names))
+			for _, name := range names {
+				values, _ := cfg.UpProfile(name)
+				rows = append(rows, []string{name, formatProfileValues(values)})
+			}
+			utils.PrintTable([]string{"NAME", "SETTINGS"}, rows)
+			return nil
+		},
+	}
+}
+
+func configProfileShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <name>",
+		Short: "Print a profile's settings",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+
+			values, ok := cfg.UpProfile(args[0])
+			if !ok {
+				return fmt.Errorf("profile %q not found", args[0])
+			}
+			for _, key := range sortedKeys(values) {
+				fmt.Printf("%s = %s\n", key, values[key])
+			}
+			return nil
+		},
+	}
+}
+
+func configProfileDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+
+			if !cfg.DeleteUpProfile(args[0]) {
+				return fmt.Errorf("profile %q not found", args[0])
+			}
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+
+			fmt.Printf("Profile %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+func formatProfileValues(values map[string]string) string {
+	parts := make([]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		parts = append(parts, key+"="+values[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,376 @@
+package debugcmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/doctor"
+	"github.com/fosrl/cli/internal/fingerprint"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/redact"
+	"github.com/fosrl/cli/internal/service"
+	versionpkg "github.com/fosrl/cli/internal/version"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// maxLogBytes is how much of the end of each log file is included.
+	maxLogBytes = 20 << 20
+	// commandTimeout bounds each system command run for the bundle.
+	commandTimeout = 10 * time.Second
+)
+
+func debugBundleCmd() *cobra.Command {
+	var (
+		output string
+		logs   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Write a support bundle",
+		Long: `Write a tar.gz archive with what support needs to diagnose a problem:
+
+  - the most recent client logs, including rotated ones, and the event journals
+  - the status of each running client
+  - the config and accounts files, with tokens and secrets removed
+  - version and server information
+  - device fingerprint and posture checks
+  - routing and DNS state
+  - the results of 'pangolin doctor'
+
+Session tokens, client secrets and other credentials are replaced with [REDACTED] everywhere in the bundle, including the logs. Review the archive before sharing it. Run with sudo to include the status of a client started with sudo.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+			if output == "" {
+				output = "pangolin-debug-" + time.Now().Format("20060102-150405") + ".tar.gz"
+			}
+
+			if err := writeBundle(cfg, output, logs); err != nil {
+				logger.Error("Error: %v", err)
+				return err
+			}
+			logger.Success("Support bundle written to %s", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Archive `path` (default: pangolin-debug-<time>.tar.gz in the current directory)")
+	cmd.Flags().IntVar(&logs, "logs", 7, "Number of most recent client log files to include")
+
+	return cmd
+}
+
+// bundle writes files into a gzipped tar archive under a single top-level
+// directory, redacting each of them.
+type bundle struct {
+	tw       *tar.Writer
+	root     string
+	redactor *redact.Redactor
+	modTime  time.Time
+	problems []string
+}
+
+// add redacts data and writes it as name. Data that would still contain a
+// known secret is left out.
+func (b *bundle) add(name string, data []byte) {
+	data = b.redactor.Text(data)
+	if b.redactor.Leaks(data) {
+		b.problem(name, fmt.Errorf("left out because it could not be redacted"))
+		return
+	}
+	hdr := &tar.Header{
+		Name:    b.root + "/" + name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: b.modTime,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		b.problem(name, err)
+		return
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		b.problem(name, err)
+	}
+}
+
+// addJSON writes v as redacted JSON.
+func (b *bundle) addJSON(name string, v any) {
+	data, err := b.redactor.Value(v)
+	if err != nil {
+		b.problem(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+// addJSONFile writes the JSON file at path, redacted, as name.
+func (b *bundle) addJSONFile(name, path string) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		b.problem(name, err)
+		return
+	}
+	redacted, err := b.redactor.JSON(data)
+	if err != nil {
+		// Never fall back to the raw file: it may hold credentials.
+		b.problem(name, fmt.Errorf("not valid JSON, left out: %w", err))
+		return
+	}
+	b.add(name, redacted)
+}
+
+func (b *bundle) problem(what string, err error) {
+	b.problems = append(b.problems, fmt.Sprintf("%s: %v", what, err))
+}
+
+func writeBundle(cfg *config.Config, output string, logs int) (err error) {
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(output)
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	now := time.Now()
+	b := &bundle{
+		tw:       tar.NewWriter(gz),
+		root:     strings.TrimSuffix(strings.TrimSuffix(filepath.Base(output), ".gz"), ".tar"),
+		redactor: redact.New(),
+		modTime:  now.Truncate(time.Second),
+	}
+
+	env := doctor.LoadEnv(cfg)
+	b.redactor.Add(knownSecrets(env)...)
+
+	logger.Info("Collecting configuration")
+	if dir, err := config.GetPangolinConfigDir(); err == nil {
+		b.addJSONFile("config.json", filepath.Join(dir, "config.json"))
+		b.addJSONFile("accounts.json", filepath.Join(dir, "accounts.json"))
+	}
+	b.addJSON("version.json", versionInfo(env, now))
+
+	logger.Info("Collecting client status")
+	for _, inst := range olm.RunningInstances() {
+		status, err := inst.Client().GetStatus()
+		if err != nil {
+			b.problem("status/"+inst.DisplayName(), err)
+			continue
+		}
+		b.addJSON("status/"+inst.DisplayName()+".json", status)
+	}
+
+	logger.Info("Collecting logs")
+	addLogs(b, cfg, logs)
+
+	logger.Info("Collecting device and network state")
+	b.addJSON("fingerprint.json", map[string]any{
+		"fingerprint": fingerprint.GatherFingerprintInfo().ToMap(),
+		"postures":    fingerprint.GatherPostureChecks().ToMap(),
+	})
+	addSystemState(b)
+
+	logger.Info("Running diagnostics")
+	b.addJSON("doctor.json", doctor.Run(env))
+
+	if len(b.problems) > 0 {
+		b.add("errors.txt", []byte(strings.Join(b.problems, "\n")+"\n"))
+	}
+
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// knownSecrets returns the credential values this machine knows about, so
+// they can be removed from free text such as logs.
+func knownSecrets(env doctor.Env) []string {
+	var secrets []string
+	if env.Accounts != nil {
+		env.Accounts.ResolveSecrets()
+		for _, account := range env.Accounts.Accounts {
+			secrets = append(secrets, account.SessionToken)
+			if account.OlmCredentials != nil {
+				secrets = append(secrets, account.OlmCredentials.Secret)
+			}
+		}
+	}
+	secrets = append(secrets, os.Getenv("CLIENT_SECRET"))
+
+	// The service environment file is only readable by root.
+	if f, err := os.Open(service.EnvFilePath); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), "=")
+			if ok && redact.IsSensitiveKey(key) {
+				secrets = append(secrets, strings.Trim(value, `'"`))
+			}
+		}
+		f.Close()
+	}
+	return secrets
+}
+
+func versionInfo(env doctor.Env, now time.Time) map[string]any {
+	info := map[string]any{
+		"version":   versionpkg.Version,
+		"goVersion": runtime.Version(),
+		"os":        runtime.GOOS,
+		"arch":      runtime.GOARCH,
+		"time":      now.UTC().Format(time.RFC3339),
+	}
+	if env.Accounts != nil {
+		if account, err := env.Accounts.ActiveAccount(); err == nil && account != nil {
+			info["server"] = account.Host
+			if account.ServerInfo != nil {
+				info["serverInfo"] = account.ServerInfo
+			}
+		}
+	}
+	if env.API != nil {
+		if serverInfo, err := env.API.GetServerInfo(); err == nil {
+			info["serverInfo"] = serverInfo
+		}
+	}
+	return info
+}
+
+// addLogs adds the n most recently written client logs, and the event
+// journals, from the log directory.
+func addLogs(b *bundle, cfg *config.Config, n int) {
+	dir := cfg.EventJournalDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			b.problem("logs", err)
+		}
+		return
+	}
+
+	type logFile struct {
+		name    string
+		modTime time.Time
+	}
+	var clientLogs []logFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, "client") && strings.HasSuffix(name, ".log"):
+			if info, err := e.Info(); err == nil {
+				clientLogs = append(clientLogs, logFile{name, info.ModTime()})
+			}
+		case strings.HasPrefix(name, "events") && strings.HasSuffix(name, ".jsonl"):
+			addTail(b, "logs/"+name, filepath.Join(dir, name))
+		}
+	}
+
+	sort.Slice(clientLogs, func(a, c int) bool { return clientLogs[a].modTime.After(clientLogs[c].modTime) })
+	for i, l := range clientLogs {
+		if i == n {
+			break
+		}
+		addTail(b, "logs/"+l.name, filepath.Join(dir, l.name))
+	}
+}
+
+// addTail adds up to maxLogBytes from the end of the file at path.
+func addTail(b *bundle, name, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		b.problem(name, err)
+		return
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() > maxLogBytes {
+		if _, err := f.Seek(info.Size()-maxLogBytes, io.SeekStart); err != nil {
+			b.problem(name, err)
+			return
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		b.problem(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+// systemCommands are run to capture routing and DNS state, keyed by the
+// file their output is stored in.
+func systemCommands() map[string][]string {
+	switch runtime.GOOS {
+	case "linux":
+		return map[string][]string{
+			"ip-addr.txt":    {"ip", "addr"},
+			"ip-route.txt":   {"ip", "route", "show", "table", "all"},
+			"ip-6-route.txt": {"ip", "-6", "route", "show", "table", "all"},
+			"ip-rule.txt":    {"ip", "rule"},
+			"resolvectl.txt": {"resolvectl", "status"},
+		}
+	case "darwin":
+		return map[string][]string{
+			"ifconfig.txt": {"ifconfig"},
+			"netstat.txt":  {"netstat", "-rn"},
+			"scutil.txt":   {"scutil", "--dns"},
+		}
+	case "windows":
+		return map[string][]string{
+			"ipconfig.txt": {"ipconfig", "/all"},
+			"route.txt":    {"route", "print"},
+		}
+	}
+	return nil
+}
+
+func addSystemState(b *bundle) {
+	for name, argv := range systemCommands() {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput()
+		cancel()
+		if err != nil && len(out) == 0 {
+			b.problem("system/"+name, err)
+			continue
+		}
+		b.add("system/"+name, out)
+	}
+
+	for _, path := range []string{"/etc/resolv.conf", "/etc/resolv.conf.olm.backup", "/etc/NetworkManager/conf.d/olm-dns.conf"} {
+		if runtime.GOOS == "windows" {
+			break
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		b.add("system/"+strings.ReplaceAll(strings.TrimPrefix(path, "/"), "/", "_"), data)
+	}
+}
@@ -0,0 +1,125 @@
+package debugcmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fosrl/cli/internal/config"
+)
+
+func TestWriteBundleRedactsSecrets(t *testing.T) {
+	const (
+		sessionToken = "session-token-0123456789abcdef"
+		olmSecret    = "olm-secret-fedcba9876543210"
+	)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SUDO_USER", "")
+	t.Setenv("CLIENT_SECRET", "")
+
+	dir := filepath.Join(home, ".config", "pangolin")
+	writeFile(t, filepath.Join(dir, "config.json"), map[string]any{
+		"secret_backend":         "plaintext",
+		"disable_companion_mode": true,
+		"disable_update_check":   true,
+	})
+	writeFile(t, filepath.Join(dir, "accounts.json"), map[string]any{
+		"activeUserId": "user1",
+		"accounts": map[string]any{
+			"user1": map[string]any{
+				"userId":       "user1",
+				"host":         "http://127.0.0.1:1",
+				"email":        "user@example.com",
+				"sessionToken": sessionToken,
+				"orgId":        "org1",
+				"olmCredentials": map[string]any{
+					"id":     "olm1",
+					"secret": olmSecret,
+				},
+			},
+		},
+	})
+	logLine := "connecting with token " + sessionToken + " and secret " + olmSecret + "\n"
+	if err := os.MkdirAll(filepath.Join(dir, "logs"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logs", "client.log"), []byte(logLine), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := writeBundle(cfg, output, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	members := readBundle(t, output)
+	for _, name := range []string{"bundle/accounts.json", "bundle/logs/client.log"} {
+		if _, ok := members[name]; !ok {
+			t.Errorf("bundle has no %s", name)
+		}
+	}
+	for name, data := range members {
+		for _, secret := range []string{sessionToken, olmSecret} {
+			if strings.Contains(data, secret) {
+				t.Errorf("%s contains %q", name, secret)
+			}
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBundle returns the contents of every file in the archive at path,
+// keyed by name.
+func readBundle(t *testing.T, path string) map[string]string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	members := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return members
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members[hdr.Name] = string(data)
+	}
+}
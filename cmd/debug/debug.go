@@ -0,0 +1,15 @@
+package debugcmd
+
+import "github.com/spf13/cobra"
+
+// DebugCmd returns the `pangolin debug` command.
+func DebugCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "debug",
+		Short: "Collect diagnostics for support",
+	}
+
+	cmd.AddCommand(debugBundleCmd())
+
+	return cmd
+}
@@ -0,0 +1,83 @@
+package doctorcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/doctor"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var statusStyles = map[doctor.Status]lipgloss.Style{
+	doctor.Pass: lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorSuccess)),
+	doctor.Warn: lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorWarning)),
+	doctor.Fail: lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorError)),
+	doctor.Skip: lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorLightGray)),
+}
+
+var fixStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorLightGray))
+
+// DoctorCmd returns the `pangolin doctor` command.
+func DoctorCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose configuration and connectivity problems",
+		Long: `Run a series of checks on the CLI configuration, the account, the server connection and the local client, and suggest a fix for each one that does not pass.
+
+The checks cover the config and accounts files, server reachability, clock skew against the server, the session, the client credentials, /dev/net/tun, the client control sockets, DNS overrides left behind by a crashed client, the tunnel interface and its routes, and the configured match domains.
+
+Some checks need root to inspect a client started with sudo; rerun with sudo if they are skipped. The command exits with status 1 when a check fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results := doctor.Run(doctor.LoadEnv(config.ConfigFromContext(cmd.Context())))
+
+			if asJSON {
+				data, err := json.MarshalIndent(results, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				printResults(results)
+			}
+
+			for _, r := range results {
+				if r.Status == doctor.Fail {
+					cmd.SilenceErrors = true
+					return errors.New("some checks failed")
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the results as JSON")
+
+	return cmd
+}
+
+func printResults(results []doctor.Result) {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Check))
+	}
+
+	counts := map[doctor.Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		label := statusStyles[r.Status].Render(fmt.Sprintf("%-4s", r.Status))
+		fmt.Printf("%s  %-*s  %s\n", label, width, r.Check, r.Detail)
+		if r.Fix != "" {
+			fmt.Printf("      %-*s  %s\n", width, "", fixStyle.Render("→ "+r.Fix))
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[doctor.Pass], counts[doctor.Warn], counts[doctor.Fail], counts[doctor.Skip])
+}
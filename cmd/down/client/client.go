@@ -2,39 +2,93 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fosrl/cli/internal/config"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/service"
 	"github.com/fosrl/cli/internal/tui"
 	"github.com/spf13/cobra"
 )
 
+type ClientDownCmdOpts struct {
+	Instance string
+	All      bool
+}
+
 func ClientDownCmd() *cobra.Command {
+	opts := ClientDownCmdOpts{}
+
 	cmd := &cobra.Command{
 		Use:   "client",
 		Short: "Stop the client connection",
-		Long:  "Stop the currently running client connection",
+		Long:  "Stop the currently running client connection. When several client instances are running, choose one with --instance or stop them all with --all.",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := clientDownMain(cmd); err != nil {
+			if err := clientDownMain(cmd, &opts); err != nil {
 				os.Exit(1)
 			}
 		},
 	}
 
+	cmd.Flags().StringVar(&opts.Instance, "instance", "", "Stop the named client `instance`")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Stop every running client instance")
+
 	return cmd
 }
 
-func clientDownMain(cmd *cobra.Command) error {
+func clientDownMain(cmd *cobra.Command, opts *ClientDownCmdOpts) error {
+	if opts.Instance != "" && opts.All {
+		err := errors.New("--instance and --all options conflict")
+		logger.Error("Error: %v", err)
+		return err
+	}
+
+	var instances []olm.Instance
+	if opts.Instance != "" {
+		instances = []olm.Instance{olm.NewInstance(olm.InstanceName(opts.Instance))}
+	} else {
+		instances = olm.RunningInstances()
+		if len(instances) > 1 && !opts.All {
+			names := make([]string, len(instances))
+			for i, inst := range instances {
+				names[i] = inst.DisplayName()
+			}
+			err := fmt.Errorf("%d clients are running (%s)", len(instances), strings.Join(names, ", "))
+			logger.Error("Error: %v", err)
+			logger.Info("Pass --instance [name] to stop one of them or --all to stop them all.")
+			return err
+		}
+	}
+
+	// Default to the default instance so the "not running" message below
+	// is shown when nothing is running.
+	if len(instances) == 0 {
+		instances = []olm.Instance{olm.NewInstance("")}
+	}
+
+	var firstErr error
+	for _, inst := range instances {
+		if err := stopInstance(cmd, inst); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func stopInstance(cmd *cobra.Command, inst olm.Instance) error {
 	cfg := config.ConfigFromContext(cmd.Context())
 
-	// Get socket path from config or use default
-	client := olm.NewClient("")
+	client := inst.Client()
 
 	// Check if client is running
 	if !client.IsRunning() {
 		err := errors.New("no client is currently running")
+		if inst.Name != "" {
+			err = fmt.Errorf("client instance %q is not running", inst.Name)
+		}
 		logger.Info("Error: %v", err)
 		return err
 	}
@@ -52,6 +106,19 @@ func clientDownMain(cmd *cobra.Command) error {
 		return err
 	}
 
+	// Stop an installed client service through its init system, which
+	// waits for the client to exit. The service runs the default instance.
+	if m, ok := service.Installed(); ok && inst.Name == "" {
+		if st, err := m.Status(); err == nil && st.Active {
+			if err := m.Stop(); err != nil {
+				logger.Error("Error: failed to stop the client service: %v", err)
+				return err
+			}
+			logger.Success("Client service stopped")
+			return nil
+		}
+	}
+
 	// Send exit signal
 	exitResp, err := client.Exit()
 	if err != nil {
@@ -61,8 +128,9 @@ func clientDownMain(cmd *cobra.Command) error {
 
 	// Show log preview until process stops
 	completed, _, err := tui.NewLogPreview(tui.LogPreviewConfig{
-		LogFile: cfg.LogFile,
-		Header:  "Shutting down client...",
+		LogFile:    cfg.ClientLogFile(inst.Name),
+		SocketPath: inst.SocketPath,
+		Header:     "Shutting down client...",
 		ExitCondition: func(client *olm.Client, status *olm.StatusResponse) (bool, bool) {
 			// Exit when process is no longer running (socket doesn't exist)
 			if !client.IsRunning() {
@@ -0,0 +1,106 @@
+package eventscmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/events"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// EventsCmd returns the `pangolin events` command.
+func EventsCmd() *cobra.Command {
+	var (
+		follow   bool
+		since    string
+		asJSON   bool
+		instance string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Show client connection events",
+		Long: `Show the connection events recorded by running clients: the tunnel connecting and disconnecting, registration errors, and sites being added, going up or down, or switching between direct, relay and local paths.
+
+Events are kept as JSON Lines next to the client log. With --follow, new events are printed as they are recorded; with --json, each event is printed as one JSON object per line.
+
+Examples:
+  pangolin events --since 1h
+  pangolin events --follow --json | jq 'select(.type == "peer_path_changed")'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+
+			var from time.Time
+			if since != "" {
+				t, err := parseSince(since)
+				if err != nil {
+					return err
+				}
+				from = t
+			}
+
+			show := func(e events.Event) {
+				if instance != "" && e.Instance != instance {
+					return
+				}
+				if asJSON {
+					line, _ := json.Marshal(e)
+					fmt.Println(string(line))
+					return
+				}
+				fmt.Printf("%s  %-10s %-18s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Instance, e.Type, e.Summary())
+			}
+
+			dir := cfg.EventJournalDir()
+			recorded, err := events.Read(dir, from)
+			if err != nil {
+				logger.Error("Error: failed to read events: %v", err)
+				return err
+			}
+			for _, e := range recorded {
+				show(e)
+			}
+			if !follow {
+				if len(recorded) == 0 && !asJSON {
+					logger.Info("No events recorded")
+				}
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return events.Follow(ctx, dir, show)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Print new events as they are recorded")
+	cmd.Flags().StringVar(&since, "since", "", "Show events newer than a `duration` (such as 1h) or an RFC 3339 time")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print events as JSON Lines")
+	cmd.Flags().StringVar(&instance, "instance", "", "Show only events of the named client `instance`")
+
+	return cmd
+}
+
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		var days int
+		if _, err := fmt.Sscanf(s, "%dd", &days); err == nil {
+			return time.Now().AddDate(0, 0, -days), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration such as 30m, 1h or 7d, or an RFC 3339 time", s)
+}
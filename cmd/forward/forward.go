@@ -0,0 +1,273 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/socks5"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// siteConnectTimeout is how long a new connection waits for the
+	// resource's site to come up before it is dropped.
+	siteConnectTimeout = 30 * time.Second
+	dialTimeout        = 5 * time.Second
+	retryInterval      = 500 * time.Millisecond
+	watchInterval      = 2 * time.Second
+)
+
+var errNoClientRunning = errors.New("No client is currently running. Start the client first.")
+
+// portMapping is one LOCAL:REMOTE argument. A zero Local port picks a free
+// port.
+type portMapping struct {
+	Local  int
+	Remote int
+}
+
+// parsePortMapping parses "PORT", "LOCAL:REMOTE" or ":REMOTE".
+func parsePortMapping(spec string) (portMapping, error) {
+	local, remote, found := strings.Cut(spec, ":")
+	if !found {
+		remote = local
+	}
+
+	var m portMapping
+	var err error
+	if m.Remote, err = parsePort(remote, false); err != nil {
+		return m, fmt.Errorf("invalid port mapping %q: %w", spec, err)
+	}
+	if m.Local, err = parsePort(local, true); err != nil {
+		return m, fmt.Errorf("invalid port mapping %q: %w", spec, err)
+	}
+	return m, nil
+}
+
+func parsePort(s string, allowZero bool) (int, error) {
+	if s == "" && allowZero {
+		return 0, nil
+	}
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 || (port == 0 && !allowZero) {
+		return 0, fmt.Errorf("%q is not a valid port", s)
+	}
+	return port, nil
+}
+
+func ForwardCmd() *cobra.Command {
+	opts := struct {
+		Address string
+		OrgID   string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "forward <resource> <[LOCAL:]REMOTE>...",
+		Short: "Forward local ports to a private resource",
+		Long: `Listen on local ports and forward each connection to a private resource through the running client.
+
+The client is asked to connect to the resource's site just in time. Each new connection retries for up to 30 seconds while the site becomes reachable, so the forward keeps working across tunnel interruptions.
+
+The client does not report which site serves the resource, so the connect request is sent again whenever a connection to the resource fails and whenever any connected site drops, even when it serves other resources.
+
+The resource alias is resolved by the system resolver, which the client points at its DNS proxy unless DNS override is disabled.
+
+Examples:
+  pangolin forward my-db.internal 5432
+  pangolin forward my-db.internal 15432:5432
+  pangolin forward my-cache.internal 6379 :8080`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resource := args[0]
+			mappings := make([]portMapping, 0, len(args)-1)
+			for _, spec := range args[1:] {
+				m, err := parsePortMapping(spec)
+				if err != nil {
+					return err
+				}
+				mappings = append(mappings, m)
+			}
+
+			accountStore := config.AccountStoreFromContext(cmd.Context())
+			orgID, err := utils.ResolveOrgID(accountStore, opts.OrgID)
+			if err != nil {
+				return err
+			}
+
+			client := olm.ClientForOrg(orgID)
+			if !client.IsRunning() {
+				return errNoClientRunning
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			f := &forwarder{client: client, resource: resource}
+			return f.run(ctx, opts.Address, mappings)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Address, "address", "127.0.0.1", "Local `address` to listen on")
+	cmd.Flags().StringVar(&opts.OrgID, "org", "", "Organization ID (default: selected organization)")
+
+	return cmd
+}
+
+// forwarder proxies local listeners to one resource.
+type forwarder struct {
+	client   *olm.Client
+	resource string
+
+	mu sync.Mutex
+}
+
+// connect asks the client to connect the resource's site. Requests are
+// serialized so that a burst of failing connections sends one at a time.
+func (f *forwarder) connect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Older olm API servers do not support JIT connect; keep going.
+	if _, err := f.client.JITConnectByResourceID(f.resource); err != nil {
+		logger.Warning("%v", err)
+	}
+}
+
+func (f *forwarder) run(ctx context.Context, address string, mappings []portMapping) error {
+	var listeners []net.Listener
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	for _, m := range mappings {
+		bind := net.JoinHostPort(address, strconv.Itoa(m.Local))
+		l, err := net.Listen("tcp", bind)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", bind, err)
+		}
+		listeners = append(listeners, l)
+
+		target := net.JoinHostPort(f.resource, strconv.Itoa(m.Remote))
+		logger.Info("Forwarding from %s -> %s", l.Addr(), target)
+		go f.accept(ctx, l, target)
+	}
+
+	f.connect()
+	f.watch(ctx)
+	return nil
+}
+
+func (f *forwarder) accept(ctx context.Context, l net.Listener, target string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			upstream, err := f.dial(ctx, target)
+			if err != nil {
+				logger.Warning("Forward to %s failed: %v", target, err)
+				conn.Close()
+				return
+			}
+			socks5.Pipe(conn, upstream)
+		}()
+	}
+}
+
+// dial connects to target, retrying while the resource's site comes up.
+// After the first failure the client is asked to connect the site again in
+// case the peer dropped.
+func (f *forwarder) dial(ctx context.Context, target string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, siteConnectTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	for attempt := 0; ; attempt++ {
+		attemptCtx, attemptCancel := context.WithTimeout(ctx, dialTimeout)
+		conn, err := dialer.DialContext(attemptCtx, "tcp", target)
+		attemptCancel()
+		if err == nil {
+			return conn, nil
+		}
+		logger.Debug("Dial %s failed: %v", target, err)
+
+		if attempt == 0 {
+			f.connect()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for the site to connect: %w", err)
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// watch polls the client until ctx is done and asks it to reconnect the
+// resource's site when a connected peer drops. Which peers serve the
+// resource is not known, so a drop of any of them counts.
+func (f *forwarder) watch(ctx context.Context) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	connected := map[int]string{}
+	clientDown := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status, err := f.client.GetStatus()
+		if err != nil {
+			if !clientDown {
+				logger.Warning("Lost contact with the client: %v", err)
+				clientDown = true
+			}
+			continue
+		}
+		if clientDown {
+			logger.Info("Client is reachable again")
+			clientDown = false
+			f.connect()
+		}
+
+		dropped := false
+		for id, name := range connected {
+			// A site removed from the client counts as dropped.
+			if peer, ok := status.PeerStatuses[id]; !ok || !peer.Connected {
+				logger.Warning("Site %s disconnected; reconnecting", name)
+				delete(connected, id)
+				dropped = true
+			}
+		}
+		for id, peer := range status.PeerStatuses {
+			if peer.Connected {
+				name := peer.SiteName
+				if name == "" {
+					name = strconv.Itoa(id)
+				}
+				connected[id] = name
+			}
+		}
+		if dropped {
+			f.connect()
+		}
+	}
+}
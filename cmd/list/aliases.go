@@ -65,6 +65,20 @@ func aliasesCmd() *cobra.Command {
 	return cmd
 }
 
+// FetchAliases returns every approved alias the user can reach in orgID. When
+// labels is non-empty only aliases for resources carrying one of those labels
+// are returned.
+func FetchAliases(apiClient *api.Client, orgID string, labels []string) ([]string, error) {
+	data, err := fetchAllAliases(apiClient, orgID, aliasesFetchOptions{
+		labelFilter: labels,
+		status:      "approved",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.Aliases, nil
+}
+
 func fetchAllAliases(apiClient *api.Client, orgID string, requested aliasesFetchOptions) (*api.ListUserResourceAliasesData, error) {
 	effective, clientSideFilter, err := resolveAliasesFetchOptions(apiClient, orgID, requested)
 	if err != nil {
@@ -11,12 +11,14 @@ import (
 
 	"github.com/fosrl/cli/internal/config"
 	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
 	"github.com/spf13/cobra"
 )
 
 type ClientLogsCmdOpts struct {
-	Follow bool
-	Lines  int
+	Follow   bool
+	Lines    int
+	Instance string
 }
 
 func ClientLogsCmd() *cobra.Command {
@@ -35,6 +37,7 @@ func ClientLogsCmd() *cobra.Command {
 
 	cmd.Flags().BoolVarP(&opts.Follow, "follow", "f", false, "Follow log output (like tail -f)")
 	cmd.Flags().IntVarP(&opts.Lines, "lines", "n", 0, "Number of lines to show (0 = all lines, only used with -f to show lines before following)")
+	cmd.Flags().StringVar(&opts.Instance, "instance", "", "Show the logs of the named client `instance` (default: the only running instance, or the default one)")
 
 	return cmd
 }
@@ -42,9 +45,17 @@ func ClientLogsCmd() *cobra.Command {
 func clientLogsMain(cmd *cobra.Command, opts *ClientLogsCmdOpts) error {
 	cfg := config.ConfigFromContext(cmd.Context())
 
+	instance := olm.InstanceName(opts.Instance)
+	if opts.Instance == "" {
+		if running := olm.RunningInstances(); len(running) == 1 {
+			instance = running[0].Name
+		}
+	}
+	logFile := cfg.ClientLogFile(instance)
+
 	if opts.Follow {
 		// Follow the log file
-		if err := watchLogFile(cfg.LogFile, opts.Lines); err != nil {
+		if err := watchLogFile(logFile, opts.Lines); err != nil {
 			logger.Error("Error: %v", err)
 			return err
 		}
@@ -55,13 +66,13 @@ func clientLogsMain(cmd *cobra.Command, opts *ClientLogsCmdOpts) error {
 	// Just print the current log file contents
 	if opts.Lines > 0 {
 		// Show last N lines
-		if err := printLastLines(cfg.LogFile, opts.Lines); err != nil {
+		if err := printLastLines(logFile, opts.Lines); err != nil {
 			logger.Error("Error: %v", err)
 			return err
 		}
 	} else {
 		// Show all lines
-		if err := printLogFile(cfg.LogFile); err != nil {
+		if err := printLogFile(logFile); err != nil {
 			logger.Error("Error: %v", err)
 			return err
 		}
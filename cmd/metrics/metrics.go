@@ -0,0 +1,121 @@
+package metricscmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/metrics"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/spf13/cobra"
+)
+
+// MetricsCmd returns the `pangolin metrics` command.
+func MetricsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Export client status as metrics",
+	}
+
+	cmd.AddCommand(metricsServeCmd())
+
+	return cmd
+}
+
+func metricsServeCmd() *cobra.Command {
+	var (
+		listen    string
+		instances []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve client status in the Prometheus format",
+		Long: `Serve the status of the running clients on /metrics in the Prometheus text format.
+
+The status is read from each client's control socket on every scrape, so the exporter can be started before the client and keeps working across restarts. Every running instance is exported unless --instance names the ones to watch; a named instance that is not running is reported with pangolin_client_up 0.
+
+Exported gauges:
+  pangolin_client_up, pangolin_client_info, pangolin_client_connected,
+  pangolin_client_registered, pangolin_client_peers,
+  pangolin_peer_up, pangolin_peer_relay, pangolin_peer_local,
+  pangolin_peer_rtt_seconds, pangolin_peer_last_seen_seconds
+
+Example alert on a site that fell back to a relay:
+  pangolin_peer_up == 1 and pangolin_peer_relay == 1`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler(func() []olm.Instance {
+				return watchedInstances(instances)
+			}))
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				_, _ = w.Write([]byte(`<html><body><a href="/metrics">Metrics</a></body></html>`))
+			})
+
+			server := &http.Server{
+				Addr:              listen,
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- server.ListenAndServe()
+			}()
+			logger.Info("Serving metrics on http://%s/metrics", listen)
+
+			select {
+			case err := <-errCh:
+				logger.Error("Metrics server failed: %v", err)
+				return err
+			case <-ctx.Done():
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", "127.0.0.1:9877", "Address to serve metrics on")
+	cmd.Flags().StringSliceVar(&instances, "instance", nil, "Export only the named client `instance`s (repeatable)")
+
+	return cmd
+}
+
+// watchedInstances returns the instances named in names, or the running
+// instances when names is empty. With nothing running the default instance
+// is returned so that scrapes report the client as down.
+func watchedInstances(names []string) []olm.Instance {
+	if len(names) > 0 {
+		out := make([]olm.Instance, len(names))
+		for i, name := range names {
+			if name == "default" {
+				name = ""
+			}
+			out[i] = olm.NewInstance(olm.InstanceName(name))
+		}
+		return out
+	}
+	if running := olm.RunningInstances(); len(running) > 0 {
+		return running
+	}
+	return []olm.Instance{olm.NewInstance("")}
+}
@@ -5,7 +5,9 @@ package resetdns
 import (
 	"errors"
 	"os"
+	"slices"
 
+	"github.com/fosrl/cli/internal/killswitch"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/fosrl/cli/internal/olm"
 	dnsOverride "github.com/fosrl/olm/dns/override"
@@ -13,26 +15,31 @@ import (
 )
 
 // ResetDNSCmd returns the `pangolin reset-dns` command which forcibly
-// removes any stale DNS override left behind by a crashed client.
+// removes any stale DNS override and kill switch left behind by a crashed
+// client.
 func ResetDNSCmd() *cobra.Command {
 	var interfaceName string
 	var force bool
 
 	cmd := &cobra.Command{
 		Use:   "reset-dns",
-		Short: "Force-clear stale DNS overrides",
+		Short: "Force-clear stale DNS overrides and kill switch rules",
 		Long: `Forcibly clear stale DNS overrides left behind by a crashed or
 stuck client. This restores your system DNS to its original
 configuration.
 
+The kill switch rules clients keep in place after they fail are
+removed too, for every recorded interface, letting traffic leave
+without the tunnel again.
+
 By default this command refuses to run when a client is still
 active; use --force to override that check.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			client := olm.NewClient("")
-			if client.IsRunning() && !force {
+			running := len(olm.RunningInstances()) > 0
+			if running && !force {
 				return errors.New("a client is currently running; stop it first with 'pangolin down' or rerun with --force")
 			}
-			if client.IsRunning() && force {
+			if running && force {
 				logger.Warning("Client appears to still be running; attempting reset anyway because --force was passed")
 			}
 
@@ -44,9 +51,42 @@ active; use --force to override that check.`,
 				logger.Error("DNS reset failed: %v", err)
 				return err
 			}
-
 			logger.Success("DNS configuration reset")
-			return nil
+
+			// Every client installs its own kill switch, and with
+			// --kill-switch=all any one of them cuts the host off, so remove
+			// all that are recorded along with the one for --interface.
+			interfaces := []string{interfaceName}
+			paths, err := killswitch.StatePaths()
+			if err != nil {
+				logger.Warning("Failed to list kill switch state: %v", err)
+			}
+			for _, path := range paths {
+				rules, err := killswitch.Load(path)
+				if err != nil || rules == nil || killswitch.ValidateInterface(rules.Interface) != nil {
+					logger.Warning("Removing unreadable kill switch state %s", path)
+					_ = os.Remove(path)
+					continue
+				}
+				if !slices.Contains(interfaces, rules.Interface) {
+					interfaces = append(interfaces, rules.Interface)
+				}
+			}
+
+			var errs []error
+			for _, iface := range interfaces {
+				removed, err := killswitch.Remove(iface)
+				if err != nil {
+					logger.Error("Kill switch removal for %s failed: %v", iface, err)
+					errs = append(errs, err)
+					continue
+				}
+				_ = os.Remove(killswitch.StatePath(iface))
+				if removed {
+					logger.Success("Kill switch removed for %s", iface)
+				}
+			}
+			return errors.Join(errs...)
 		},
 	}
 
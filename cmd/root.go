@@ -13,12 +13,21 @@ import (
 	"github.com/fosrl/cli/cmd/authdaemon"
 	companioncmd "github.com/fosrl/cli/cmd/companion"
 	configcmd "github.com/fosrl/cli/cmd/config"
+	debugcmd "github.com/fosrl/cli/cmd/debug"
+	doctorcmd "github.com/fosrl/cli/cmd/doctor"
 	"github.com/fosrl/cli/cmd/down"
+	eventscmd "github.com/fosrl/cli/cmd/events"
+	"github.com/fosrl/cli/cmd/forward"
 	"github.com/fosrl/cli/cmd/list"
 	"github.com/fosrl/cli/cmd/logs"
+	metricscmd "github.com/fosrl/cli/cmd/metrics"
 	"github.com/fosrl/cli/cmd/resetdns"
+	"github.com/fosrl/cli/cmd/rsync"
+	"github.com/fosrl/cli/cmd/run"
 	"github.com/fosrl/cli/cmd/scp"
 	selectcmd "github.com/fosrl/cli/cmd/select"
+	servicecmd "github.com/fosrl/cli/cmd/service"
+	sftpcmd "github.com/fosrl/cli/cmd/sftp"
 	"github.com/fosrl/cli/cmd/ssh"
 	"github.com/fosrl/cli/cmd/status"
 	"github.com/fosrl/cli/cmd/up"
@@ -82,9 +91,23 @@ func RootCommand(initResources bool) (*cobra.Command, error) {
 	if watchdogCmd := watchdog.WatchdogCmd(); watchdogCmd != nil {
 		cmd.AddCommand(watchdogCmd)
 	}
+	if serviceCmd := servicecmd.ServiceCmd(); serviceCmd != nil {
+		cmd.AddCommand(serviceCmd)
+	}
+	if runCmd := run.RunCmd(); runCmd != nil {
+		cmd.AddCommand(runCmd)
+	}
+
+	cmd.AddCommand(debugcmd.DebugCmd())
+	cmd.AddCommand(doctorcmd.DoctorCmd())
+	cmd.AddCommand(eventscmd.EventsCmd())
+	cmd.AddCommand(metricscmd.MetricsCmd())
 
 	cmd.AddCommand(ssh.SSHCmd())
 	cmd.AddCommand(scp.SCPCmd())
+	cmd.AddCommand(rsync.RsyncCmd())
+	cmd.AddCommand(sftpcmd.SFTPCmd())
+	cmd.AddCommand(forward.ForwardCmd())
 	cmd.AddCommand(update.UpdateCmd())
 	cmd.AddCommand(version.VersionCmd())
 	cmd.AddCommand(login.LoginCmd())
@@ -114,7 +137,10 @@ func RootCommand(initResources bool) (*cobra.Command, error) {
 
 func commandNeedsAuthInit(cmd *cobra.Command) bool {
 	for c := cmd; c != nil; c = c.Parent() {
-		if c.Name() == "companion" || c.Name() == "config" {
+		// Only the top-level commands; nested ones such as `ssh config`
+		// still need auth.
+		isTopLevel := c.HasParent() && !c.Parent().HasParent()
+		if isTopLevel && (c.Name() == "companion" || c.Name() == "config" || c.Name() == "debug" || c.Name() == "doctor" || c.Name() == "events" || c.Name() == "metrics") {
 			return false
 		}
 	}
@@ -141,6 +167,16 @@ func commandExemptFromCompanionReady(cmd *cobra.Command) bool {
 	return false
 }
 
+// commandOwnsStdout reports whether cmd uses stdout as a data stream (such as
+// an OpenSSH ProxyCommand or --json output), so banners and update notices
+// must not be printed.
+func commandOwnsStdout(cmd *cobra.Command) bool {
+	if asJSON, err := cmd.Flags().GetBool("json"); err == nil && asJSON {
+		return true
+	}
+	return cmd.Name() == "proxy" && commandHasAncestor(cmd, "ssh")
+}
+
 func commandHasAncestor(cmd *cobra.Command, name string) bool {
 	for p := cmd.Parent(); p != nil; p = p.Parent() {
 		if p.Name() == name {
@@ -185,8 +221,10 @@ func mainCommandPreRun(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("configuration not loaded")
 	}
 
-	if err := notice.ShowPending(cfg); err != nil {
-		logger.Debug("Failed to show pending notices: %v", err)
+	if !commandOwnsStdout(cmd) {
+		if err := notice.ShowPending(cfg); err != nil {
+			logger.Debug("Failed to show pending notices: %v", err)
+		}
 	}
 
 	if commandNeedsAuthInit(cmd) {
@@ -204,6 +242,11 @@ func mainCommandPreRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Nothing else may be printed when stdout carries a data stream.
+	if commandOwnsStdout(cmd) {
+		return nil
+	}
+
 	// Skip update checks when running self-update or companion commands.
 	cmdName := cmd.Name()
 	if cmdName == "update" || !commandNeedsAuthInit(cmd) {
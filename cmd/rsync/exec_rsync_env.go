@@ -0,0 +1,40 @@
+package rsync
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// envRsyncBinary overrides the rsync(1) executable on all platforms when non-empty.
+const envRsyncBinary = "PANGOLIN_RSYNC_BINARY"
+
+// execRsyncSearchPaths are fallback locations for the rsync executable when not in PATH.
+var execRsyncSearchPaths = []string{
+	"/usr/bin/rsync",
+	"/usr/local/bin/rsync",
+	"/opt/homebrew/bin/rsync",
+}
+
+func findExecRsyncPath() (string, error) {
+	if p := strings.TrimSpace(os.Getenv(envRsyncBinary)); p != "" {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", fmt.Errorf("%s=%q: %w", envRsyncBinary, p, err)
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("%s=%q: is a directory", envRsyncBinary, p)
+		}
+		return p, nil
+	}
+	if path, err := exec.LookPath("rsync"); err == nil {
+		return path, nil
+	}
+	for _, p := range execRsyncSearchPaths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("rsync executable not found in PATH or in common locations; set %s", envRsyncBinary)
+}
@@ -0,0 +1,203 @@
+package rsync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	sshcmd "github.com/fosrl/cli/cmd/ssh"
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	errNoRemoteOperand  = errors.New("no remote operand found; at least one of source or destination must be a remote path (host:path or user@host:path)")
+	errRsyncOperands    = errors.New("rsync requires a source and a destination; example: pangolin rsync -av ./dist/ my-server.internal:/srv/app/")
+	errMultipleResource = errors.New("rsync can only copy to or from one resource at a time")
+)
+
+func RsyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rsync [rsync flags] <source>... <destination>",
+		Short: "Run rsync over SSH using just-in-time SSH certificates",
+		Long: `Run rsync(1) over SSH. Generates a key pair and signs it just-in-time, then executes the system rsync with a remote shell (-e) that authenticates with the signed key.
+
+Use the resource alias or identifier as the host in remote operands, exactly as you would with regular rsync. All flags are passed to rsync(1). An -e/--rsh option you pass is kept and the key options are appended to it; use -e "ssh -p 2222" to connect to a non-default port.
+Examples:
+  pangolin rsync -av ./dist/ my-server.internal:/srv/app/
+  pangolin rsync -az --delete deploy@my-server.internal:/var/backups/ ./backups/
+
+rsync-daemon (host::module) and rsync:// operands are not supported.
+
+Set PANGOLIN_RSYNC_BINARY to the full path of rsync(1) to override PATH lookup on all platforms. The remote shell is ssh(1), found as for pangolin ssh (PANGOLIN_SSH_BINARY), unless -e names another.`,
+		// rsync's own flags (-h, -p, -e, ...) would clash with Cobra's parsing.
+		DisableFlagParsing: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 || (len(args) == 1 && (args[0] == "-h" || args[0] == "--help")) {
+				return c.Help()
+			}
+
+			parsed := parseRsyncArgs(args)
+			if len(parsed.Operands) < 2 {
+				return errRsyncOperands
+			}
+			username, resourceID, err := remoteTarget(parsed.Operands)
+			if err != nil {
+				return err
+			}
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			target, err := sshcmd.PrepareTarget(apiClient, accountStore, resourceID, username)
+			if err != nil {
+				return err
+			}
+
+			exitCode, err := runRsync(target, parsed)
+			if err != nil {
+				return err
+			}
+			os.Exit(exitCode)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// remoteTarget returns the user and resource named by the remote operands,
+// which must all refer to the same resource.
+func remoteTarget(operands []string) (username, resourceID string, err error) {
+	for _, operand := range operands {
+		hostSpec, _, ok := splitRemoteOperand(operand)
+		if !ok {
+			continue
+		}
+		u, h := "", hostSpec
+		if before, after, hasAt := strings.Cut(hostSpec, "@"); hasAt {
+			u, h = before, after
+		}
+		if resourceID != "" && (h != resourceID || u != username) {
+			return "", "", errMultipleResource
+		}
+		username, resourceID = u, h
+	}
+	if resourceID == "" {
+		return "", "", errNoRemoteOperand
+	}
+	return username, resourceID, nil
+}
+
+// splitRemoteOperand splits [user@]host:path the way rsync(1) recognizes
+// remote operands: a colon before any slash. Daemon operands (host::module)
+// are not remote shell operands and are left alone.
+func splitRemoteOperand(s string) (hostSpec, pathPart string, ok bool) {
+	idx := strings.IndexByte(s, ':')
+	if idx <= 0 || strings.ContainsRune(s[:idx], '/') {
+		return "", "", false
+	}
+	// A Windows drive letter such as C:\dir is a local path.
+	if idx == 1 && len(s) > 2 && (s[2] == '\\' || s[2] == '/') {
+		return "", "", false
+	}
+	if strings.HasPrefix(s[idx+1:], ":") {
+		return "", "", false
+	}
+	return s[:idx], s[idx+1:], true
+}
+
+func runRsync(target *sshcmd.Target, parsed rsyncArgs) (int, error) {
+	rsyncPath, err := findExecRsyncPath()
+	if err != nil {
+		return 1, err
+	}
+
+	keyPath, certPath, cleanup, err := sshcmd.WriteExecKeyFiles(target.PrivateKeyPEM, target.Certificate)
+	if err != nil {
+		return 1, err
+	}
+	defer cleanup()
+
+	rsh, err := buildRemoteShell(parsed.RemoteShell, target, keyPath, certPath)
+	if err != nil {
+		return 1, err
+	}
+
+	argv := []string{rsyncPath, "-e", rsh}
+	argv = append(argv, parsed.Options...)
+	for _, operand := range parsed.Operands {
+		argv = append(argv, rewriteOperand(operand, target))
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, fmt.Errorf("run rsync: %w", err)
+	}
+	return 0, nil
+}
+
+// buildRemoteShell returns the -e command for rsync: the user's remote shell
+// (ssh by default) with the JIT identity and host key options appended. In
+// native mode the user's ssh options are filtered like pangolin ssh's.
+func buildRemoteShell(userShell string, target *sshcmd.Target, keyPath, certPath string) (string, error) {
+	var words []string
+	if userShell != "" {
+		var err error
+		words, err = splitRemoteShell(userShell)
+		if err != nil {
+			return "", fmt.Errorf("parse -e %q: %w", userShell, err)
+		}
+	}
+	if len(words) == 0 {
+		sshPath, err := sshcmd.FindExecSSHPath()
+		if err != nil {
+			return "", err
+		}
+		words = []string{sshPath}
+	}
+
+	if target.Native() && len(words) > 1 {
+		pt, stripped := sshcmd.FilterForNativeMode(sshcmd.ParseOpenSSHPassThrough(words[1:]))
+		if len(stripped) > 0 {
+			logger.Warning("The following options are not supported by the native SSH server and were ignored: %s", sshcmd.NativeStrippedWarning(stripped))
+		}
+		words = append(words[:1], pt.Options...)
+	}
+
+	words = append(words, sshcmd.JITIdentityOptions(keyPath, certPath)...)
+	words = append(words, sshcmd.HostKeyCheckingOptions(target.KnownHostsFile, target.Native())...)
+
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = quoteRemoteShellWord(w)
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// rewriteOperand replaces the resource in a remote operand with the signed
+// user and connected hostname.
+func rewriteOperand(operand string, target *sshcmd.Target) string {
+	if _, pathPart, ok := splitRemoteOperand(operand); ok {
+		host := target.SignData.Hostname
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if target.SignData.User != "" {
+			host = target.SignData.User + "@" + host
+		}
+		return host + ":" + pathPart
+	}
+	return operand
+}
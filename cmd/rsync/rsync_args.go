@@ -0,0 +1,144 @@
+package rsync
+
+import (
+	"errors"
+	"strings"
+)
+
+// rsyncArgs is an rsync(1) command line split into options, the remote shell
+// given with -e/--rsh (removed from Options), and operands.
+type rsyncArgs struct {
+	Options     []string
+	RemoteShell string
+	Operands    []string
+}
+
+// rsyncShortWithArg are the short rsync options that take a value.
+const rsyncShortWithArg = "efBTM@"
+
+// rsyncLongWithArg are the long rsync options that take a value, which may
+// be given as --opt=value or as the following argument.
+var rsyncLongWithArg = map[string]struct{}{
+	"--address": {}, "--backup-dir": {}, "--block-size": {}, "--bwlimit": {},
+	"--checksum-choice": {}, "--checksum-seed": {}, "--chmod": {}, "--chown": {},
+	"--compare-dest": {}, "--compress-choice": {}, "--compress-level": {},
+	"--contimeout": {}, "--copy-dest": {}, "--debug": {}, "--exclude": {},
+	"--exclude-from": {}, "--files-from": {}, "--filter": {}, "--groupmap": {},
+	"--iconv": {}, "--include": {}, "--include-from": {}, "--info": {},
+	"--link-dest": {}, "--log-file": {}, "--log-file-format": {},
+	"--max-alloc": {}, "--max-delete": {}, "--max-size": {}, "--min-size": {},
+	"--modify-window": {}, "--only-write-batch": {}, "--out-format": {},
+	"--outbuf": {}, "--partial-dir": {}, "--password-file": {}, "--port": {},
+	"--protocol": {}, "--read-batch": {}, "--remote-option": {}, "--rsh": {},
+	"--rsync-path": {}, "--skip-compress": {}, "--sockopts": {},
+	"--stop-after": {}, "--stop-at": {}, "--suffix": {}, "--temp-dir": {},
+	"--timeout": {}, "--usermap": {}, "--write-batch": {},
+}
+
+// parseRsyncArgs classifies args so operands can be rewritten without
+// mistaking option values for them.
+func parseRsyncArgs(args []string) rsyncArgs {
+	var out rsyncArgs
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--":
+			out.Options = append(out.Options, a)
+			out.Operands = append(out.Operands, args[i+1:]...)
+			return out
+		case strings.HasPrefix(a, "--"):
+			name, value, hasValue := strings.Cut(a, "=")
+			_, takesValue := rsyncLongWithArg[name]
+			if takesValue && !hasValue && i+1 < len(args) {
+				i++
+				value, hasValue = args[i], true
+			}
+			if name == "--rsh" && hasValue {
+				out.RemoteShell = value
+				continue
+			}
+			out.Options = append(out.Options, a)
+			if takesValue && a == name && hasValue {
+				out.Options = append(out.Options, value)
+			}
+		case strings.HasPrefix(a, "-") && a != "-":
+			// A short group such as -avz; the first letter that takes a
+			// value consumes the rest of the token or the next argument.
+			j := strings.IndexAny(a[1:], rsyncShortWithArg) + 1
+			if j == 0 {
+				out.Options = append(out.Options, a)
+				continue
+			}
+			value := a[j+1:]
+			if value == "" && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			if a[j] == 'e' {
+				if j > 1 {
+					out.Options = append(out.Options, a[:j])
+				}
+				out.RemoteShell = value
+				continue
+			}
+			out.Options = append(out.Options, a[:j+1])
+			if value != "" {
+				out.Options = append(out.Options, value)
+			}
+		default:
+			out.Operands = append(out.Operands, a)
+		}
+	}
+	return out
+}
+
+// splitRemoteShell splits an -e command into words the way rsync(1) does:
+// on spaces, with single- or double-quoted sections in which a doubled quote
+// character stands for itself.
+func splitRemoteShell(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				cur.WriteByte(c)
+			} else {
+				quote = 0
+			}
+		case quote != 0:
+			cur.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// quoteRemoteShellWord quotes w for splitRemoteShell's rules.
+func quoteRemoteShellWord(w string) string {
+	if w != "" && !strings.ContainsAny(w, ` '"`) {
+		return w
+	}
+	return `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+}
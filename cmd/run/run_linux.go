@@ -0,0 +1,484 @@
+//go:build linux
+
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// registerTimeout bounds how long the tunnel has to come up before the
+	// command is abandoned.
+	registerTimeout = 30 * time.Second
+	stopTimeout     = 10 * time.Second
+	pollInterval    = 500 * time.Millisecond
+	routeInterval   = time.Second
+)
+
+var errRootRequired = errors.New("this command must be run as root to create a network namespace; rerun it with sudo")
+
+// RunCmd returns the `pangolin run` command, which runs a command in a
+// network namespace that only reaches the tunnel.
+func RunCmd() *cobra.Command {
+	opts := struct {
+		OrgID    string
+		Instance string
+		KeepRoot bool
+		DNS      string
+		RunAs    string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "run [flags] -- <command> [args...]",
+		Short: "Run a command in a network namespace that only sees the tunnel",
+		Long: `Start a client of its own, move its interface into a new network namespace and run the command there.
+
+Inside the namespace the command sees only the loopback and Pangolin interfaces: private resources are reachable, everything else is not. Aliases are resolved by the client's DNS proxy through the namespace's resolv.conf, so the host's DNS settings and routes are never changed. When the client does not report the proxy's address, pass it, or another resolver reachable through the tunnel, with --dns. Sites the client connects to while the command runs are routed into the namespace as they come up.
+
+Everything is torn down when the command exits, and its exit status is returned. Each run uses a separate client instance, so runs for different organizations do not conflict. The command runs as the user who invoked sudo; pass --keep-root to run it as root.
+
+Examples:
+  sudo pangolin run -- ./integration-tests.sh
+  sudo pangolin run --org staging -- curl http://api.internal/health`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.RunAs != "" {
+				return execAs(opts.RunAs, args)
+			}
+			if os.Geteuid() != 0 {
+				return errRootRequired
+			}
+			if _, err := exec.LookPath("ip"); err != nil {
+				return errors.New("the ip command from iproute2 is required")
+			}
+
+			name := opts.Instance
+			if name == "" {
+				name = fmt.Sprintf("run-%d", os.Getpid())
+			}
+			r := &runner{
+				instance:  olm.NewInstance(olm.InstanceName(name)),
+				namespace: "pangolin-" + olm.InstanceName(name),
+			}
+			r.iface = olm.InstanceInterfaceName(r.instance.Name)
+			if opts.DNS != "" {
+				addr, err := netip.ParseAddr(opts.DNS)
+				if err != nil {
+					return fmt.Errorf("invalid --dns address %q", opts.DNS)
+				}
+				r.dnsServer = addr.String()
+			}
+			if !opts.KeepRoot {
+				uid, gid := os.Getenv("SUDO_UID"), os.Getenv("SUDO_GID")
+				if uid != "" && uid != "0" && gid != "" {
+					r.runAs = uid + ":" + gid
+				}
+			}
+			if r.instance.Client().IsRunning() {
+				return fmt.Errorf("client instance %q is already running", r.instance.Name)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			code, err := r.run(ctx, opts.OrgID, args)
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				stop()
+				os.Exit(code)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.OrgID, "org", "", "Organization ID (default: selected organization)")
+	cmd.Flags().StringVar(&opts.Instance, "instance", "", "Client instance `name` (default: run-<pid>)")
+	cmd.Flags().StringVar(&opts.DNS, "dns", "", "DNS server `address` for the namespace (default: the DNS proxy the client reports)")
+	cmd.Flags().BoolVar(&opts.KeepRoot, "keep-root", false, "Run the command as root instead of as the user who invoked sudo")
+	// --run-as starts the command inside the namespace; see execAs.
+	cmd.Flags().StringVar(&opts.RunAs, "run-as", "", "")
+	_ = cmd.Flags().MarkHidden("run-as")
+
+	return cmd
+}
+
+// runner holds the client instance and namespace of one run.
+type runner struct {
+	instance  olm.Instance
+	namespace string
+	iface     string
+	// runAs is the uid:gid the command runs as, or empty to run it as
+	// root.
+	runAs string
+	// dnsServer overrides the DNS server the client reports.
+	dnsServer string
+}
+
+// run starts the tunnel, runs args inside the namespace and tears both down.
+// It returns the command's exit code.
+func (r *runner) run(ctx context.Context, orgID string, args []string) (int, error) {
+	if err := r.startClient(orgID); err != nil {
+		return 0, err
+	}
+	// The client is stopped first so that its interface is gone before
+	// the namespace is deleted.
+	namespaced := false
+	defer func() {
+		r.stopClient()
+		if namespaced {
+			r.deleteNamespace()
+		}
+	}()
+
+	status, err := r.waitRegistered(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// Without a DNS server aliases could not be resolved in the
+	// namespace, and the host's resolver is not reachable from it.
+	if r.dnsServer != "" {
+		status.dnsServer = r.dnsServer
+	}
+	if status.dnsServer == "" {
+		return 0, errors.New("the client did not report its DNS server, so aliases could not be resolved in the namespace; pass --dns with the address of a resolver reachable through the tunnel")
+	}
+
+	namespaced = true
+	if err := r.createNamespace(status); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go r.syncRoutes(ctx)
+
+	return r.exec(args)
+}
+
+// startClient starts a detached client for the run's instance. DNS is
+// never overridden; the namespace gets its own resolv.conf instead.
+func (r *runner) startClient(orgID string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	args := []string{"up", "client", "--silent", "--instance", r.instance.Name, "--override-dns=false"}
+	if orgID != "" {
+		args = append(args, "--org", orgID)
+	}
+
+	logger.Info("Starting client instance %q", r.instance.Name)
+	up := exec.Command(executable, args...)
+	up.Stdin = os.Stdin
+	up.Stdout = os.Stdout
+	up.Stderr = os.Stderr
+	if err := up.Run(); err != nil {
+		return fmt.Errorf("failed to start the client: %w", err)
+	}
+	return nil
+}
+
+func (r *runner) stopClient() {
+	client := r.instance.Client()
+	if _, err := client.Exit(); err != nil {
+		logger.Warning("Failed to stop client instance %q: %v", r.instance.Name, err)
+		return
+	}
+	for deadline := time.Now().Add(stopTimeout); time.Now().Before(deadline); time.Sleep(pollInterval) {
+		if !client.IsRunning() {
+			return
+		}
+	}
+	logger.Warning("Client instance %q did not stop; run `pangolin down client --instance %s`", r.instance.Name, r.instance.Name)
+}
+
+// waitRegistered waits for the client to register and configure its
+// interface, which it does before reporting itself registered.
+func (r *runner) waitRegistered(ctx context.Context) (*networkSettings, error) {
+	client := r.instance.Client()
+	deadline := time.Now().Add(registerTimeout)
+	for time.Now().Before(deadline) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		status, err := client.GetStatus()
+		if err == nil {
+			if status.Error != nil {
+				return nil, fmt.Errorf("the client failed to register: %s", status.Error.Message)
+			}
+			if status.Registered {
+				settings := parseNetworkSettings(status)
+				if len(settings.addresses) > 0 {
+					return settings, nil
+				}
+			}
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil, errors.New("timed out waiting for the client to connect; run `pangolin logs client` to see why")
+}
+
+// createNamespace moves the client's interface into a new namespace and
+// configures its addresses, routes and resolver there. Moving the interface
+// removes it and its routes from the host.
+func (r *runner) createNamespace(settings *networkSettings) error {
+	if err := ip("netns", "add", r.namespace); err != nil {
+		return err
+	}
+	if err := ip("link", "set", "dev", r.iface, "netns", r.namespace); err != nil {
+		return err
+	}
+	if err := r.ipNS("link", "set", "dev", "lo", "up"); err != nil {
+		return err
+	}
+	for _, addr := range settings.addresses {
+		if err := r.ipNS("addr", "add", addr, "dev", r.iface); err != nil {
+			return err
+		}
+	}
+	if err := r.ipNS("link", "set", "dev", r.iface, "up"); err != nil {
+		return err
+	}
+	for _, route := range settings.routes {
+		if err := r.ipNS("route", "replace", route, "dev", r.iface); err != nil {
+			return err
+		}
+	}
+
+	// ip netns exec bind-mounts /etc/netns/<name>/resolv.conf over
+	// /etc/resolv.conf for the command.
+	dir := filepath.Join("/etc/netns", r.namespace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "resolv.conf"), []byte("nameserver "+settings.dnsServer+"\n"), 0o644)
+}
+
+func (r *runner) deleteNamespace() {
+	if err := ip("netns", "delete", r.namespace); err != nil {
+		logger.Warning("%v", err)
+	}
+	_ = os.RemoveAll(filepath.Join("/etc/netns", r.namespace))
+}
+
+// syncRoutes routes sites the client adds while the command runs into the
+// namespace; the client's own attempts fail once the interface has moved.
+func (r *runner) syncRoutes(ctx context.Context) {
+	ticker := time.NewTicker(routeInterval)
+	defer ticker.Stop()
+
+	routed := map[string]bool{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status, err := r.instance.Client().GetStatus()
+		if err != nil {
+			continue
+		}
+		current := map[string]bool{}
+		for _, route := range parseNetworkSettings(status).routes {
+			current[route] = true
+			if routed[route] {
+				continue
+			}
+			if err := r.ipNS("route", "replace", route, "dev", r.iface); err != nil {
+				logger.Debug("%v", err)
+				continue
+			}
+			routed[route] = true
+		}
+		for route := range routed {
+			if !current[route] {
+				_ = r.ipNS("route", "delete", route, "dev", r.iface)
+				delete(routed, route)
+			}
+		}
+	}
+}
+
+// exec runs args in the namespace, forwarding interrupts to it, and returns
+// its exit code. Entering the namespace takes root, so to run the command
+// as another user this executable is started there first and drops to
+// that user before starting it.
+func (r *runner) exec(args []string) (int, error) {
+	cmdArgs := []string{"netns", "exec", r.namespace}
+	if r.runAs != "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get executable path: %w", err)
+		}
+		cmdArgs = append(cmdArgs, executable, "run", "--run-as", r.runAs, "--")
+	}
+	cmdArgs = append(cmdArgs, args...)
+	c := exec.Command("ip", cmdArgs...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	// The command gets the terminal's signals itself; keep them from
+	// ending this process before the teardown.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	if err := c.Start(); err != nil {
+		return 0, fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	go func() {
+		for sig := range sigs {
+			_ = c.Process.Signal(sig)
+		}
+	}()
+
+	err := c.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// execAs replaces this process with args, running as the user and group in
+// spec ("uid:gid") with the user's supplementary groups and home directory.
+func execAs(spec string, args []string) error {
+	uidStr, gidStr, _ := strings.Cut(spec, ":")
+	uid, err := strconv.Atoi(uidStr)
+	if err != nil {
+		return fmt.Errorf("invalid user %q", spec)
+	}
+	gid, err := strconv.Atoi(gidStr)
+	if err != nil {
+		return fmt.Errorf("invalid user %q", spec)
+	}
+
+	groups := []int{gid}
+	env := os.Environ()
+	if u, err := user.LookupId(uidStr); err == nil {
+		if ids, err := u.GroupIds(); err == nil {
+			for _, id := range ids {
+				if g, err := strconv.Atoi(id); err == nil && g != gid {
+					groups = append(groups, g)
+				}
+			}
+		}
+		env = setEnv(env, "HOME", u.HomeDir)
+		env = setEnv(env, "USER", u.Username)
+		env = setEnv(env, "LOGNAME", u.Username)
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("failed to set groups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("failed to set group: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("failed to set user: %w", err)
+	}
+	return syscall.Exec(path, args, env)
+}
+
+// setEnv returns env with key set to value, replacing any earlier value.
+func setEnv(env []string, key, value string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			out = append(out, kv)
+		}
+	}
+	return append(out, key+"="+value)
+}
+
+func (r *runner) ipNS(args ...string) error {
+	return ip(append([]string{"-n", r.namespace}, args...)...)
+}
+
+func ip(args ...string) error {
+	out, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// networkSettings is the part of the client's network settings the
+// namespace is configured from, in ip(8) notation.
+type networkSettings struct {
+	addresses []string
+	routes    []string
+	dnsServer string
+}
+
+// parseNetworkSettings reads the interface addresses, routes and DNS server
+// the client reports.
+func parseNetworkSettings(status *olm.StatusResponse) *networkSettings {
+	var raw struct {
+		DNSServers      []string `json:"dns_servers"`
+		IPv4Addresses   []string `json:"ipv4_addresses"`
+		IPv4SubnetMasks []string `json:"ipv4_subnet_masks"`
+		IPv4Routes      []struct {
+			DestinationAddress string `json:"destination_address"`
+			SubnetMask         string `json:"subnet_mask"`
+		} `json:"ipv4_included_routes"`
+	}
+	settings := &networkSettings{}
+	if data, err := json.Marshal(status.NetworkSettings); err == nil {
+		_ = json.Unmarshal(data, &raw)
+	}
+
+	for i, addr := range raw.IPv4Addresses {
+		mask := ""
+		if i < len(raw.IPv4SubnetMasks) {
+			mask = raw.IPv4SubnetMasks[i]
+		}
+		settings.addresses = append(settings.addresses, cidr(addr, mask))
+	}
+	for _, route := range raw.IPv4Routes {
+		settings.routes = append(settings.routes, cidr(route.DestinationAddress, route.SubnetMask))
+	}
+
+	if len(raw.DNSServers) > 0 {
+		settings.dnsServer = raw.DNSServers[0]
+	}
+	return settings
+}
+
+// cidr joins an address and a dotted subnet mask; without a mask the
+// address is a single host.
+func cidr(addr, mask string) string {
+	ones := 32
+	if m := net.ParseIP(mask).To4(); m != nil {
+		ones, _ = net.IPv4Mask(m[0], m[1], m[2], m[3]).Size()
+	}
+	return fmt.Sprintf("%s/%d", addr, ones)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package run
+
+import "github.com/spf13/cobra"
+
+// RunCmd is only supported on Linux, which has network namespaces.
+func RunCmd() *cobra.Command {
+	return nil
+}
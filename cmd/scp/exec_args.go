@@ -5,6 +5,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+
+	sshcmd "github.com/fosrl/cli/cmd/ssh"
 )
 
 func buildExecSCPArgs(scpPath string, opts RunOpts, keyPath, certPath string) []string {
@@ -22,7 +24,7 @@ func buildExecSCPArgs(scpPath string, opts RunOpts, keyPath, certPath string) []
 		"-o", "PasswordAuthentication=no",
 		"-o", "KbdInteractiveAuthentication=no",
 	)
-	args = append(args, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "-o", "LogLevel=ERROR")
+	args = append(args, sshcmd.HostKeyCheckingOptions(opts.KnownHostsFile, opts.StrictHostKeys)...)
 	if opts.Port > 0 {
 		args = append(args, "-P", strconv.Itoa(opts.Port))
 	}
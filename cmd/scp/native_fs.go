@@ -0,0 +1,155 @@
+package scp
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// transferFS is one end of a built-in transfer: the local file system or the
+// remote host over SFTP.
+type transferFS interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.FileInfo, error)
+	Open(name string) (io.ReadSeekCloser, error)
+	// OpenWriter opens name for writing, creating it if needed. With
+	// truncate unset, existing content is kept so a transfer can resume.
+	OpenWriter(name string, truncate bool) (io.WriteSeeker, io.Closer, error)
+	Mkdir(name string) error
+	// Replace renames oldname to newname, replacing newname if it exists.
+	Replace(oldname, newname string) error
+	Chtimes(name string, atime, mtime time.Time) error
+	Chmod(name string, mode fs.FileMode) error
+	Join(elem ...string) string
+	Base(name string) string
+	// Display returns name as the user would write it in an scp operand.
+	Display(name string) string
+}
+
+type localFS struct{}
+
+func (localFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+func (localFS) ReadDir(name string) ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (localFS) Open(name string) (io.ReadSeekCloser, error) { return os.Open(name) }
+
+func (localFS) OpenWriter(name string, truncate bool) (io.WriteSeeker, io.Closer, error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flags, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+func (localFS) Mkdir(name string) error { return os.Mkdir(name, 0o755) }
+
+func (localFS) Replace(oldname, newname string) error { return os.Rename(oldname, newname) }
+
+func (localFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+func (localFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+
+func (localFS) Join(elem ...string) string { return filepath.Join(elem...) }
+
+func (localFS) Base(name string) string { return filepath.Base(name) }
+
+func (localFS) Display(name string) string { return name }
+
+// remoteFS is the remote end of a transfer. Relative paths are resolved
+// against the login directory by the SFTP server.
+type remoteFS struct {
+	client *sftp.Client
+	host   string
+}
+
+func (r remoteFS) Stat(name string) (fs.FileInfo, error) { return r.client.Stat(name) }
+
+func (r remoteFS) ReadDir(name string) ([]fs.FileInfo, error) { return r.client.ReadDir(name) }
+
+func (r remoteFS) Open(name string) (io.ReadSeekCloser, error) { return r.client.Open(name) }
+
+func (r remoteFS) OpenWriter(name string, truncate bool) (io.WriteSeeker, io.Closer, error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	f, err := r.client.OpenFile(name, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+func (r remoteFS) Mkdir(name string) error { return r.client.Mkdir(name) }
+
+// Replace prefers the posix-rename extension; plain SFTP rename refuses to
+// overwrite an existing file.
+func (r remoteFS) Replace(oldname, newname string) error {
+	if err := r.client.PosixRename(oldname, newname); err == nil {
+		return nil
+	}
+	if err := r.client.Remove(newname); err != nil && !isNotExist(err) {
+		return err
+	}
+	return r.client.Rename(oldname, newname)
+}
+
+func (r remoteFS) Chtimes(name string, atime, mtime time.Time) error {
+	return r.client.Chtimes(name, atime, mtime)
+}
+
+func (r remoteFS) Chmod(name string, mode fs.FileMode) error { return r.client.Chmod(name, mode) }
+
+func (r remoteFS) Join(elem ...string) string { return path.Join(elem...) }
+
+func (r remoteFS) Base(name string) string { return path.Base(name) }
+
+func (r remoteFS) Display(name string) string { return r.host + ":" + name }
+
+// remotePath maps the path part of a remote scp operand to an SFTP path.
+// scp(1) paths are relative to the home directory, as are SFTP paths, so a
+// leading "~/" is dropped.
+func remotePath(p string) string {
+	switch {
+	case p == "" || p == "~":
+		return "."
+	case strings.HasPrefix(p, "~/"):
+		if rest := strings.TrimLeft(p[2:], "/"); rest != "" {
+			return rest
+		}
+		return "."
+	}
+	return p
+}
+
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
@@ -0,0 +1,171 @@
+package scp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// transferReporter receives progress from the built-in transfer.
+type transferReporter interface {
+	// Start announces a file of size bytes, offset of which were already
+	// transferred by an earlier, interrupted run.
+	Start(name string, size, offset int64)
+	Add(n int64)
+	Finish(name string)
+}
+
+type quietReporter struct{}
+
+func (quietReporter) Start(string, int64, int64) {}
+func (quietReporter) Add(int64)                  {}
+func (quietReporter) Finish(string)              {}
+
+type fileStartMsg struct {
+	name         string
+	size, offset int64
+}
+
+type fileBytesMsg struct{ done int64 }
+
+type fileFinishedMsg struct{ name string }
+
+type transferDoneMsg struct{}
+
+// transferProgressModel shows a progress bar for the file being transferred
+// and prints a line for each completed file.
+type transferProgressModel struct {
+	bar     progress.Model
+	name    string
+	size    int64
+	done    int64
+	started time.Time
+	resumed int64
+}
+
+func newTransferProgressModel() transferProgressModel {
+	return transferProgressModel{bar: progress.New(progress.WithDefaultGradient(), progress.WithWidth(30))}
+}
+
+func (m transferProgressModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m transferProgressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case fileStartMsg:
+		m.name, m.size, m.done, m.resumed = msg.name, msg.size, msg.offset, msg.offset
+		m.started = time.Now()
+	case fileBytesMsg:
+		m.done = msg.done
+	case fileFinishedMsg:
+		line := fmt.Sprintf("%s  %s", msg.name, formatBytes(m.size))
+		if m.resumed > 0 {
+			line += lipgloss.NewStyle().Faint(true).Render(fmt.Sprintf(" (resumed at %s)", formatBytes(m.resumed)))
+		}
+		m.name = ""
+		return m, tea.Println(line)
+	case transferDoneMsg:
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m transferProgressModel) View() string {
+	if m.name == "" {
+		return ""
+	}
+	percent := 1.0
+	if m.size > 0 {
+		percent = float64(m.done) / float64(m.size)
+	}
+	rate := ""
+	if elapsed := time.Since(m.started).Seconds(); elapsed > 0 {
+		rate = formatBytes(int64(float64(m.done-m.resumed)/elapsed)) + "/s"
+	}
+	return fmt.Sprintf("%s %s %s/%s %s\n", m.name, m.bar.ViewAs(percent), formatBytes(m.done), formatBytes(m.size), rate)
+}
+
+// teaReporter forwards progress to a bubbletea program, throttling byte
+// counts so the display is not flooded.
+type teaReporter struct {
+	program *tea.Program
+
+	mu       sync.Mutex
+	done     int64
+	lastSent time.Time
+}
+
+func (r *teaReporter) Start(name string, size, offset int64) {
+	r.mu.Lock()
+	r.done = offset
+	r.lastSent = time.Time{}
+	r.mu.Unlock()
+	r.program.Send(fileStartMsg{name: name, size: size, offset: offset})
+}
+
+func (r *teaReporter) Add(n int64) {
+	r.mu.Lock()
+	r.done += n
+	done := r.done
+	send := time.Since(r.lastSent) >= progressInterval
+	if send {
+		r.lastSent = time.Now()
+	}
+	r.mu.Unlock()
+	if send {
+		r.program.Send(fileBytesMsg{done: done})
+	}
+}
+
+func (r *teaReporter) Finish(name string) {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	r.program.Send(fileBytesMsg{done: done})
+	r.program.Send(fileFinishedMsg{name: name})
+}
+
+// runWithProgress runs transfer while showing progress on stderr. It returns
+// transfer's result. When the user presses Ctrl+C the partially transferred
+// file is kept so the next run resumes it.
+func runWithProgress(transfer func(transferReporter) error) error {
+	program := tea.NewProgram(newTransferProgressModel(), tea.WithOutput(os.Stderr), tea.WithInput(nil))
+	reporter := &teaReporter{program: program}
+
+	result := make(chan error, 1)
+	go func() {
+		err := transfer(reporter)
+		result <- err
+		program.Send(transferDoneMsg{})
+	}()
+
+	if _, err := program.Run(); err != nil {
+		if errors.Is(err, tea.ErrInterrupted) {
+			return errors.New("transfer interrupted; run the same command again to resume")
+		}
+		return err
+	}
+	return <-result
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,355 @@
+package scp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	sshcmd "github.com/fosrl/cli/cmd/ssh"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// partialSuffix is appended to a destination file while it is being
+// written. An interrupted transfer leaves the partial file behind and the
+// next run continues from its size.
+const partialSuffix = ".pangolin-part"
+
+// nativeSCPOptions is the subset of scp(1) options the built-in transfer
+// implements, parsed from options already passed through
+// sshcmd.FilterForNativeSCPMode.
+type nativeSCPOptions struct {
+	Recursive bool   // -r
+	Preserve  bool   // -p
+	Quiet     bool   // -q
+	LimitKbps int    // -l, in Kbit/s
+	Ciphers   string // -c
+}
+
+// transferEnd is a parsed scp operand.
+type transferEnd struct {
+	fs   transferFS
+	path string
+}
+
+// RunNative copies files using the pure-Go SSH client and SFTP instead of
+// the system scp binary. opts.PrivateKeyPEM and opts.Certificate must be set
+// (JIT key + signed cert).
+//
+// Recursive copies (-r), preserved times and modes (-p), -q, -l, and -c are
+// supported. Files are written under a temporary name and renamed into place
+// when complete; an interrupted copy is resumed by the next run.
+func RunNative(opts RunOpts) (int, error) {
+	pt, stripped := sshcmd.FilterForNativeSCPMode(opts.Passthrough)
+	if len(stripped) > 0 {
+		logger.Warning("The following options are not supported by the built-in client and were ignored: %s", sshcmd.NativeStrippedWarning(stripped))
+	}
+	nativeOpts, err := parseNativeSCPOptions(pt.Options)
+	if err != nil {
+		return 1, err
+	}
+
+	addr, err := sshcmd.NativeSSHAddress(opts.Hostname, opts.Port)
+	if err != nil {
+		return 1, err
+	}
+	config, err := sshcmd.NativeSSHClientConfig(sshcmd.RunOpts{
+		User:           opts.User,
+		PrivateKeyPEM:  opts.PrivateKeyPEM,
+		Certificate:    opts.Certificate,
+		KnownHostsFile: opts.KnownHostsFile,
+	})
+	if err != nil {
+		return 1, err
+	}
+	if nativeOpts.Ciphers != "" {
+		config.Ciphers = strings.Split(nativeOpts.Ciphers, ",")
+	}
+
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return 1, fmt.Errorf("ssh dial: %w", err)
+	}
+	defer client.Close()
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		return 1, fmt.Errorf("start SFTP session (the server must enable the sftp subsystem): %w", err)
+	}
+	defer sftpClient.Close()
+
+	remote := remoteFS{client: sftpClient, host: opts.ResourceID}
+	sources, dest, err := nativeSCPOperands(pt.RemoteCommand, opts.ResourceID, remote)
+	if err != nil {
+		return 1, err
+	}
+
+	t := &nativeTransfer{opts: nativeOpts}
+	transfer := func(r transferReporter) error {
+		t.reporter = r
+		return t.copyAll(sources, dest)
+	}
+
+	if nativeOpts.Quiet || !isatty.IsTerminal(os.Stderr.Fd()) {
+		err = transfer(quietReporter{})
+	} else {
+		err = runWithProgress(transfer)
+	}
+	if err != nil {
+		return 1, err
+	}
+	if t.failed {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func parseNativeSCPOptions(options []string) (nativeSCPOptions, error) {
+	var out nativeSCPOptions
+	for i := 0; i < len(options); i++ {
+		tok := options[i]
+		switch tok {
+		case "--":
+			continue
+		case "-l", "-c":
+			if i+1 >= len(options) {
+				return out, fmt.Errorf("%s requires an argument", tok)
+			}
+			i++
+			if tok == "-c" {
+				out.Ciphers = options[i]
+				continue
+			}
+			limit, err := strconv.Atoi(options[i])
+			if err != nil || limit <= 0 {
+				return out, fmt.Errorf("invalid -l limit %q", options[i])
+			}
+			out.LimitKbps = limit
+			continue
+		}
+		for _, c := range strings.TrimPrefix(tok, "-") {
+			switch c {
+			case 'r', 'R':
+				out.Recursive = true
+			case 'p':
+				out.Preserve = true
+			case 'q':
+				out.Quiet = true
+			}
+		}
+	}
+	return out, nil
+}
+
+// nativeSCPOperands splits scp operands into sources and the destination.
+// Remote operands must name the resource the session is connected to.
+func nativeSCPOperands(operands []string, resourceID string, remote remoteFS) ([]transferEnd, transferEnd, error) {
+	if len(operands) < 2 {
+		return nil, transferEnd{}, errScpOperands
+	}
+	ends := make([]transferEnd, 0, len(operands))
+	for _, operand := range operands {
+		hostSpec, pathPart, ok := splitSCPOperand(operand)
+		if !ok {
+			ends = append(ends, transferEnd{fs: localFS{}, path: operand})
+			continue
+		}
+		if !matchesTargetHost(hostSpec, resourceID) {
+			return nil, transferEnd{}, fmt.Errorf("%s: the built-in client can only copy to and from %s", operand, resourceID)
+		}
+		ends = append(ends, transferEnd{fs: remote, path: remotePath(pathPart)})
+	}
+	return ends[:len(ends)-1], ends[len(ends)-1], nil
+}
+
+type nativeTransfer struct {
+	opts     nativeSCPOptions
+	reporter transferReporter
+	// failed is set when a file could not be copied; like scp(1), the
+	// remaining files are still attempted.
+	failed bool
+}
+
+func (t *nativeTransfer) copyAll(sources []transferEnd, dest transferEnd) error {
+	destIsDir := false
+	if info, err := dest.fs.Stat(dest.path); err == nil {
+		destIsDir = info.IsDir()
+	} else if !isNotExist(err) {
+		return fmt.Errorf("%s: %w", dest.fs.Display(dest.path), err)
+	}
+	if len(sources) > 1 && !destIsDir {
+		return fmt.Errorf("%s: not a directory", dest.fs.Display(dest.path))
+	}
+
+	for _, src := range sources {
+		info, err := src.fs.Stat(src.path)
+		if err != nil {
+			t.fail(src.fs.Display(src.path), err)
+			continue
+		}
+		target := dest.path
+		if destIsDir {
+			target = dest.fs.Join(dest.path, src.fs.Base(src.path))
+		}
+		t.copy(src.fs, src.path, info, dest.fs, target)
+	}
+	return nil
+}
+
+// copy copies src to dst, reporting and recording failures per file.
+func (t *nativeTransfer) copy(srcFS transferFS, src string, info fs.FileInfo, dstFS transferFS, dst string) {
+	switch {
+	case info.IsDir() && !t.opts.Recursive:
+		t.fail(srcFS.Display(src), errors.New("not a regular file (use -r to copy directories)"))
+	case info.IsDir():
+		t.copyDir(srcFS, src, info, dstFS, dst)
+	case !info.Mode().IsRegular():
+		t.fail(srcFS.Display(src), errors.New("not a regular file"))
+	default:
+		if err := t.copyFile(srcFS, src, info, dstFS, dst); err != nil {
+			t.fail(srcFS.Display(src), err)
+		}
+	}
+}
+
+func (t *nativeTransfer) copyDir(srcFS transferFS, src string, info fs.FileInfo, dstFS transferFS, dst string) {
+	if existing, err := dstFS.Stat(dst); err == nil {
+		if !existing.IsDir() {
+			t.fail(dstFS.Display(dst), errors.New("not a directory"))
+			return
+		}
+	} else if err := dstFS.Mkdir(dst); err != nil {
+		t.fail(dstFS.Display(dst), err)
+		return
+	}
+
+	entries, err := srcFS.ReadDir(src)
+	if err != nil {
+		t.fail(srcFS.Display(src), err)
+		return
+	}
+	for _, entry := range entries {
+		t.copy(srcFS, srcFS.Join(src, entry.Name()), entry, dstFS, dstFS.Join(dst, entry.Name()))
+	}
+
+	// Directory times last, after the entries written above touched them.
+	if t.opts.Preserve {
+		t.preserve(info, dstFS, dst)
+	}
+}
+
+// copyFile writes src to dst+partialSuffix and renames it into place. A
+// partial file left by an earlier run is continued when it is no larger than
+// the source and was written after the source was last modified.
+func (t *nativeTransfer) copyFile(srcFS transferFS, src string, info fs.FileInfo, dstFS transferFS, dst string) error {
+	partial := dst + partialSuffix
+
+	var offset int64
+	if p, err := dstFS.Stat(partial); err == nil && p.Mode().IsRegular() &&
+		p.Size() <= info.Size() && !p.ModTime().Before(info.ModTime()) {
+		offset = p.Size()
+	}
+
+	in, err := srcFS.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, closer, err := dstFS.OpenWriter(partial, offset == 0)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if _, err := in.Seek(offset, io.SeekStart); err != nil {
+			closer.Close()
+			return err
+		}
+		if _, err := out.Seek(offset, io.SeekStart); err != nil {
+			closer.Close()
+			return err
+		}
+	}
+
+	name := srcFS.Base(src)
+	t.reporter.Start(name, info.Size(), offset)
+	_, err = io.Copy(&progressWriter{w: out, reporter: t.reporter, limit: newRateLimiter(t.opts.LimitKbps)}, in)
+	if closeErr := closer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := dstFS.Replace(partial, dst); err != nil {
+		return err
+	}
+	t.reporter.Finish(name)
+
+	if t.opts.Preserve {
+		t.preserve(info, dstFS, dst)
+	}
+	return nil
+}
+
+// preserve applies the source's modification time and mode bits to dst.
+func (t *nativeTransfer) preserve(info fs.FileInfo, dstFS transferFS, dst string) {
+	if err := dstFS.Chmod(dst, info.Mode().Perm()); err != nil {
+		logger.Warning("%s: set mode: %v", dstFS.Display(dst), err)
+	}
+	if err := dstFS.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		logger.Warning("%s: set times: %v", dstFS.Display(dst), err)
+	}
+}
+
+func (t *nativeTransfer) fail(name string, err error) {
+	t.failed = true
+	logger.Error("%s: %v", name, err)
+}
+
+// progressWriter reports bytes written and applies the -l rate limit.
+type progressWriter struct {
+	w        io.Writer
+	reporter transferReporter
+	limit    *rateLimiter
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.reporter.Add(int64(n))
+	p.limit.wait(n)
+	return n, err
+}
+
+// rateLimiter keeps the average rate at or below a number of Kbit/s, as
+// scp -l does. A nil limiter does not limit.
+type rateLimiter struct {
+	bytesPerSec float64
+	start       time.Time
+	sent        int64
+}
+
+func newRateLimiter(kbps int) *rateLimiter {
+	if kbps <= 0 {
+		return nil
+	}
+	return &rateLimiter{bytesPerSec: float64(kbps) * 1000 / 8, start: time.Now()}
+}
+
+func (r *rateLimiter) wait(n int) {
+	if r == nil {
+		return
+	}
+	r.sent += int64(n)
+	due := r.start.Add(time.Duration(float64(r.sent) / r.bytesPerSec * float64(time.Second)))
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+}
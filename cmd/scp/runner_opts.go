@@ -9,5 +9,8 @@ type RunOpts struct {
 	PrivateKeyPEM string
 	Certificate   string
 	ResourceID    string
-	Passthrough   sshcmd.SSHPassthrough
+	// KnownHostsFile and StrictHostKeys check host keys as in sshcmd.RunOpts.
+	KnownHostsFile string
+	StrictHostKeys bool
+	Passthrough    sshcmd.SSHPassthrough
 }
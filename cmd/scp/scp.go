@@ -25,6 +25,7 @@ func SCPCmd() *cobra.Command {
 		ResourceID string
 		Username   string
 		Port       int
+		Builtin    bool
 	}{}
 
 	cmd := &cobra.Command{
@@ -41,8 +42,11 @@ Examples:
   pangolin scp ./local-file my-server.internal:/remote/path
   pangolin scp my-server.internal:/var/log/syslog ./syslog
   pangolin scp -r ./dir my-server.internal:~/
+  pangolin scp -P 2222 -p ./local-file my-server.internal:/remote/path
 
-Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on all platforms.`,
+Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on all platforms.
+
+With --builtin the files are copied over SFTP by the pure-Go client instead, for systems without OpenSSH. It supports -r, -p, -q, -l, and -c; other scp(1) options are ignored. Each file is written under a temporary name and moved into place when complete, so an interrupted copy resumes where it stopped when the same command is run again.`,
 		PreRunE: func(c *cobra.Command, args []string) error {
 			// Use os.Args directly so that unknown boolean scp flags (e.g. -r,
 			// -p, -v) do not cause pflag to swallow the following operand as a
@@ -60,28 +64,29 @@ Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on al
 			return nil
 		},
 		Run: func(c *cobra.Command, args []string) {
-			client := olm.NewClient("")
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				logger.Error("%v", err)
+				os.Exit(1)
+			}
+
+			// Use the client instance connected to the resource's organization.
+			client := olm.ClientForOrg(orgID)
 			if !client.IsRunning() {
 				logger.Error("%v", errNoClientRunning)
 				os.Exit(1)
 			}
 
-			apiClient := api.FromContext(c.Context())
-			accountStore := config.AccountStoreFromContext(c.Context())
-
 			// init a jit connection to the site if we need to because we might not be connected
-			_, err := client.JITConnectByResourceID(opts.ResourceID)
+			_, err = client.JITConnectByResourceID(opts.ResourceID)
 			if err != nil {
 				logger.Warning("%v", err) // keep warning behavior for backward compatibility
 			}
 
-			orgID, err := utils.ResolveOrgID(accountStore, "")
-			if err != nil {
-				logger.Error("%v", err)
-				os.Exit(1)
-			}
-
-			privPEM, _, cert, signData, err := sshcmd.GenerateAndSignKey(apiClient, orgID, opts.ResourceID, opts.Username)
+			privPEM, _, cert, signData, err := sshcmd.SignKeyCached(apiClient, accountStore, orgID, opts.ResourceID, opts.Username)
 			if err != nil {
 				logger.Error("%v", err)
 				os.Exit(1)
@@ -108,7 +113,7 @@ Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on al
 				}
 			}
 
-			pt := sshcmd.ParseOpenSSHPassThrough(rawSCPArgs())
+			pt := sshcmd.ParseOpenSSHPassThrough(stripPangolinSCPFlags(rawSCPArgs()))
 
 			// When the auth daemon is the native SSH server, restrict
 			// pass-through options to the subset it actually supports.
@@ -120,17 +125,30 @@ Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on al
 				}
 			}
 
+			knownHostsFile, err := sshcmd.KnownHostsFile(orgID, signData, opts.Port)
+			if err != nil {
+				logger.Error("%v", err)
+				os.Exit(1)
+			}
+
 			runOpts := RunOpts{
-				User:          signData.User,
-				Hostname:      signData.Hostname,
-				Port:          opts.Port,
-				PrivateKeyPEM: privPEM,
-				Certificate:   cert,
-				ResourceID:    opts.ResourceID,
-				Passthrough:   pt,
+				User:           signData.User,
+				Hostname:       signData.Hostname,
+				Port:           opts.Port,
+				PrivateKeyPEM:  privPEM,
+				Certificate:    cert,
+				ResourceID:     opts.ResourceID,
+				KnownHostsFile: knownHostsFile,
+				StrictHostKeys: signData.AuthDaemonMode == "native",
+				Passthrough:    pt,
 			}
 
-			exitCode, err := RunExec(runOpts)
+			var exitCode int
+			if opts.Builtin {
+				exitCode, err = RunNative(runOpts)
+			} else {
+				exitCode, err = RunExec(runOpts)
+			}
 			if err != nil {
 				logger.Error("%v", err)
 				os.Exit(1)
@@ -139,7 +157,9 @@ Set PANGOLIN_SCP_BINARY to the full path of scp(1) to override PATH lookup on al
 		},
 	}
 
-	cmd.Flags().IntVarP(&opts.Port, "port", "p", 0, "Remote SCP/SSH port (default: 22)")
+	// -P as in scp(1); -p is left to scp for preserving times and modes.
+	cmd.Flags().IntVarP(&opts.Port, "port", "P", 0, "Remote SCP/SSH port (default: 22)")
+	cmd.Flags().BoolVar(&opts.Builtin, "builtin", false, "Copy over SFTP with the built-in client instead of the system scp binary (supports -r, -p, -q, -l, -c)")
 
 	return cmd
 }
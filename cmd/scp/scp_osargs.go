@@ -18,6 +18,41 @@ func rawSCPArgs() []string {
 	return nil
 }
 
+// stripPangolinSCPFlags removes pangolin-only tokens so they are not treated
+// as scp(1) options or operands. --port / -P are parsed by Cobra and applied
+// via RunOpts.Port; -p is scp's own preserve flag and is kept.
+func stripPangolinSCPFlags(in []string) []string {
+	out := make([]string, 0, len(in))
+	for i := 0; i < len(in); {
+		switch {
+		case in[i] == "--builtin":
+			i++
+		case strings.HasPrefix(in[i], "--port="):
+			i++
+		case strings.HasPrefix(in[i], "-P") && isAllDigits(in[i][2:]):
+			i++
+		case (in[i] == "--port" || in[i] == "-P") && i+1 < len(in) && isAllDigits(in[i+1]):
+			i += 2
+		default:
+			out = append(out, in[i])
+			i++
+		}
+	}
+	return out
+}
+
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // countSCPOperands returns the number of non-flag positional operands in args.
 func countSCPOperands(args []string) int {
 	count := 0
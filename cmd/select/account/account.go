@@ -118,9 +118,9 @@ func accountMain(cmd *cobra.Command, opts *AccountCmdOpts) error {
 		return err
 	}
 
-	// Shut down running client only if it was started by this CLI
-	olmClient := olm.NewClient("")
-	if olmClient.IsRunning() {
+	// Shut down running clients only if they were started by this CLI
+	for _, inst := range olm.RunningInstances() {
+		olmClient := inst.Client()
 		status, err := olmClient.GetStatus()
 		if err == nil && status != nil && status.Agent == olm.AgentName {
 			logger.Info("Shutting down running client")
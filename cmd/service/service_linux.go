@@ -0,0 +1,280 @@
+//go:build linux
+
+package servicecmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/service"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var errRootRequired = errors.New("this command must be run as root; rerun it with sudo")
+
+// ServiceCmd returns the `pangolin service` command, which runs the client
+// under systemd or OpenRC.
+func ServiceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "Run the client as a system service",
+		Long: `Install the client as a systemd unit (or an OpenRC script) named ` + service.Name + `.
+
+The service runs 'pangolin up client --attach' as root with the credentials stored in ` + service.EnvFilePath + `, which only root can read. It is started at boot, restarted when it fails, and logs to the journal (journalctl -u ` + service.Name + `) or, with OpenRC, to syslog.
+
+While the service is installed, 'pangolin up' and 'pangolin down' start and stop it instead of running a client of their own.`,
+	}
+
+	cmd.AddCommand(serviceInstallCmd())
+	cmd.AddCommand(serviceUninstallCmd())
+	cmd.AddCommand(serviceStartCmd())
+	cmd.AddCommand(serviceStopCmd())
+	cmd.AddCommand(serviceStatusCmd())
+
+	return cmd
+}
+
+func serviceInstallCmd() *cobra.Command {
+	opts := struct {
+		ID       string
+		Secret   string
+		Endpoint string
+		OrgID    string
+		NoStart  bool
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "install [-- up client flags]",
+		Short: "Install and start the client service",
+		Long: `Install the client service, enable it at boot and start it.
+
+Without --id and --secret the logged-in account's client credentials and selected organization are used, as with 'pangolin up'. Flags after -- are passed to 'pangolin up client', for example:
+
+  sudo pangolin service install -- --tunnel-dns --upstream-dns 1.1.1.1
+
+Running install again replaces the service with the new settings.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (opts.ID == "") != (opts.Secret == "") {
+				return errors.New("--id and --secret must be provided together")
+			}
+			if os.Geteuid() != 0 {
+				return errRootRequired
+			}
+
+			m, err := service.Detect()
+			if err != nil {
+				return err
+			}
+
+			env := map[string]string{}
+			if opts.ID != "" {
+				if opts.Endpoint == "" {
+					return errors.New("--endpoint is required with --id and --secret")
+				}
+				env["CLIENT_ID"] = opts.ID
+				env["CLIENT_SECRET"] = opts.Secret
+				env["PANGOLIN_ENDPOINT"] = opts.Endpoint
+				if opts.OrgID != "" {
+					env["PANGOLIN_ORG"] = opts.OrgID
+				}
+			} else {
+				apiClient := api.FromContext(cmd.Context())
+				accountStore := config.AccountStoreFromContext(cmd.Context())
+				if err := accountCredentials(apiClient, accountStore, opts.Endpoint, opts.OrgID, env); err != nil {
+					return err
+				}
+			}
+
+			executable, err := os.Executable()
+			if err != nil {
+				return err
+			}
+
+			before, err := m.Status()
+			if err != nil {
+				return err
+			}
+
+			spec := service.Spec{
+				Executable: executable,
+				Args:       append([]string{"up", "client", "--attach"}, args...),
+				Env:        env,
+			}
+			if err := m.Install(spec); err != nil {
+				logger.Error("Failed to install the client service: %v", err)
+				return err
+			}
+			logger.Success("Installed %s (%s)", m.Path(), m.Name())
+
+			if opts.NoStart {
+				return nil
+			}
+			if !before.Active && olm.NewClient("").IsRunning() {
+				logger.Warning("A client started with 'pangolin up' is running; stop it with 'pangolin down', then run 'pangolin service start'")
+				return nil
+			}
+			if before.Active {
+				if err := m.Stop(); err != nil {
+					return err
+				}
+			}
+			if err := m.Start(); err != nil {
+				return err
+			}
+			logger.Success("Client service started")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.ID, "id", "", "Client ID (default: the logged-in account's client)")
+	cmd.Flags().StringVar(&opts.Secret, "secret", "", "Client secret (default: the logged-in account's client)")
+	cmd.Flags().StringVar(&opts.Endpoint, "endpoint", "", "Client endpoint (default: the logged-in account's server)")
+	cmd.Flags().StringVar(&opts.OrgID, "org", "", "Organization ID (default: selected organization if logged in)")
+	cmd.Flags().BoolVar(&opts.NoStart, "no-start", false, "Install and enable the service without starting it")
+
+	return cmd
+}
+
+// accountCredentials fills env with the active account's client credentials,
+// creating them if needed, as `pangolin up` does.
+func accountCredentials(apiClient *api.Client, accountStore *config.AccountStore, endpoint, orgID string, env map[string]string) error {
+	activeAccount, err := accountStore.ActiveAccount()
+	if err != nil {
+		logger.Error("Error: %v. Run `pangolin login` to login", err)
+		return err
+	}
+
+	newCredsGenerated, err := utils.EnsureOlmCredentials(apiClient, activeAccount)
+	if err != nil {
+		logger.Error("Failed to ensure OLM credentials: %v", err)
+		return err
+	}
+	if newCredsGenerated {
+		if err := accountStore.UpdateActiveAccount(activeAccount); err != nil {
+			logger.Error("Failed to update account in store: %v", err)
+			return err
+		}
+		if err := accountStore.Save(); err != nil {
+			logger.Error("Failed to save accounts to store: %v", err)
+			return err
+		}
+	}
+
+	if endpoint == "" {
+		endpoint = activeAccount.Host
+	}
+	if orgID == "" {
+		orgID = activeAccount.OrgID
+	}
+	if orgID == "" {
+		logger.Info("Run `pangolin select org` to select an organization or pass --org [id] to the command")
+		return errors.New("organization not selected")
+	}
+
+	env["CLIENT_ID"] = activeAccount.OlmCredentials.ID
+	env["CLIENT_SECRET"] = activeAccount.OlmCredentials.Secret
+	env["PANGOLIN_ENDPOINT"] = endpoint
+	env["PANGOLIN_ORG"] = orgID
+	env["PANGOLIN_CREDENTIALS_FROM_KEYRING"] = "1"
+	// The service reads the installing user's Pangolin configuration, as a
+	// client started by `pangolin up` through sudo does.
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		env["SUDO_USER"] = sudoUser
+	}
+	return nil
+}
+
+func serviceUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Stop and remove the client service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Geteuid() != 0 {
+				return errRootRequired
+			}
+			m, ok := service.Installed()
+			if !ok {
+				return service.ErrNotInstalled
+			}
+			if err := m.Uninstall(); err != nil {
+				logger.Error("Failed to uninstall the client service: %v", err)
+				return err
+			}
+			logger.Success("Client service uninstalled")
+			return nil
+		},
+	}
+}
+
+func serviceStartCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start",
+		Short: "Start the client service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, ok := service.Installed()
+			if !ok {
+				return service.ErrNotInstalled
+			}
+			if err := m.Start(); err != nil {
+				return err
+			}
+			logger.Success("Client service started")
+			return nil
+		},
+	}
+}
+
+func serviceStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the client service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, ok := service.Installed()
+			if !ok {
+				return service.ErrNotInstalled
+			}
+			if err := m.Stop(); err != nil {
+				return err
+			}
+			logger.Success("Client service stopped")
+			return nil
+		},
+	}
+}
+
+func serviceStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the client service status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := service.Detect()
+			if err != nil {
+				return err
+			}
+			st, err := m.Status()
+			if err != nil {
+				return err
+			}
+
+			yesNo := func(b bool) string {
+				if b {
+					return "yes"
+				}
+				return "no"
+			}
+			utils.PrintTable([]string{"SERVICE", "MANAGER", "INSTALLED", "ENABLED", "ACTIVE"}, [][]string{
+				{service.Name, m.Name(), yesNo(st.Installed), yesNo(st.Enabled), yesNo(st.Active)},
+			})
+
+			if st.Installed && m.Name() == "systemd" {
+				logger.Info("Logs: journalctl -u %s", service.Name)
+			}
+			return nil
+		},
+	}
+}
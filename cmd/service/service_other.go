@@ -0,0 +1,10 @@
+//go:build !linux
+
+package servicecmd
+
+import "github.com/spf13/cobra"
+
+// ServiceCmd is only supported on Linux.
+func ServiceCmd() *cobra.Command {
+	return nil
+}
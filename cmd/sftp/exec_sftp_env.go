@@ -0,0 +1,40 @@
+package sftp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// envSFTPBinary overrides the sftp(1) executable on all platforms when non-empty.
+const envSFTPBinary = "PANGOLIN_SFTP_BINARY"
+
+// execSFTPSearchPaths are fallback locations for the sftp executable when not in PATH.
+var execSFTPSearchPaths = []string{
+	"/usr/bin/sftp",
+	"/usr/local/bin/sftp",
+	`C:\Windows\System32\OpenSSH\sftp.exe`,
+}
+
+func findExecSFTPPath() (string, error) {
+	if p := strings.TrimSpace(os.Getenv(envSFTPBinary)); p != "" {
+		info, err := os.Stat(p)
+		if err != nil {
+			return "", fmt.Errorf("%s=%q: %w", envSFTPBinary, p, err)
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("%s=%q: is a directory", envSFTPBinary, p)
+		}
+		return p, nil
+	}
+	if path, err := exec.LookPath("sftp"); err == nil {
+		return path, nil
+	}
+	for _, p := range execSFTPSearchPaths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("sftp executable not found in PATH or in common locations; set %s", envSFTPBinary)
+}
@@ -0,0 +1,125 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	sshcmd "github.com/fosrl/cli/cmd/ssh"
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var errDestinationRequired = errors.New("sftp requires one destination; example: pangolin sftp my-server.internal or pangolin sftp user@my-server.internal:/var/log")
+
+func SFTPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sftp [sftp flags] <[user@]resource[:path]>",
+		Short: "Run an interactive sftp session using just-in-time SSH certificates",
+		Long: `Run sftp(1) in the terminal. Generates a key pair and signs it just-in-time, then executes the system OpenSSH sftp client.
+
+Use the resource alias or identifier as the host, exactly as you would with regular sftp. All flags are passed to sftp(1); use -P to connect to a non-default port.
+Examples:
+  pangolin sftp my-server.internal
+  pangolin sftp -r deploy@my-server.internal:/srv/app
+  pangolin sftp -b commands.txt my-server.internal
+
+Set PANGOLIN_SFTP_BINARY to the full path of sftp(1) to override PATH lookup on all platforms.`,
+		// sftp's own flags (-p, -r, -P, ...) would clash with Cobra's parsing.
+		DisableFlagParsing: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
+				return c.Help()
+			}
+
+			pt := sshcmd.ParseSFTPPassThrough(args)
+			if len(pt.RemoteCommand) != 1 {
+				return errDestinationRequired
+			}
+			username, resourceID, remotePath := parseSFTPDestination(pt.RemoteCommand[0])
+			if resourceID == "" {
+				return errDestinationRequired
+			}
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			target, err := sshcmd.PrepareTarget(apiClient, accountStore, resourceID, username)
+			if err != nil {
+				return err
+			}
+
+			// When the auth daemon is the native SSH server, restrict
+			// pass-through options to the subset it actually supports.
+			if target.Native() {
+				var stripped []string
+				pt, stripped = sshcmd.FilterForNativeSFTPMode(pt)
+				if len(stripped) > 0 {
+					logger.Warning("The following options are not supported by the native SSH server and were ignored: %s", sshcmd.NativeStrippedWarning(stripped))
+				}
+			}
+
+			exitCode, err := runSFTP(target, pt.Options, remotePath)
+			if err != nil {
+				return err
+			}
+			os.Exit(exitCode)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// parseSFTPDestination splits [user@]resource[:path].
+func parseSFTPDestination(dest string) (username, resourceID, remotePath string) {
+	hostSpec, remotePath, _ := strings.Cut(dest, ":")
+	if u, h, hasAt := strings.Cut(hostSpec, "@"); hasAt {
+		return u, h, remotePath
+	}
+	return "", hostSpec, remotePath
+}
+
+func runSFTP(target *sshcmd.Target, options []string, remotePath string) (int, error) {
+	sftpPath, err := findExecSFTPPath()
+	if err != nil {
+		return 1, err
+	}
+
+	keyPath, certPath, cleanup, err := sshcmd.WriteExecKeyFiles(target.PrivateKeyPEM, target.Certificate)
+	if err != nil {
+		return 1, err
+	}
+	defer cleanup()
+
+	dest := target.SignData.Hostname
+	if target.SignData.User != "" {
+		dest = target.SignData.User + "@" + dest
+	}
+	if remotePath != "" {
+		dest += ":" + remotePath
+	}
+
+	argv := []string{sftpPath}
+	argv = append(argv, sshcmd.JITIdentityOptions(keyPath, certPath)...)
+	argv = append(argv, sshcmd.HostKeyCheckingOptions(target.KnownHostsFile, target.Native())...)
+	argv = append(argv, options...)
+	argv = append(argv, dest)
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, fmt.Errorf("run sftp: %w", err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,447 @@
+//go:build !windows
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/sshcerts"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const agentSocketName = "agent.sock"
+
+// Targets that fail to sign are retried after agentRetryMin, doubling up to
+// agentRetryMax, rather than on every identity request.
+const (
+	agentRetryMin = 30 * time.Second
+	agentRetryMax = 10 * time.Minute
+)
+
+var errAgentResourceRequired = errors.New("at least one resource is required; example: pangolin ssh agent my-server.internal deploy@build.internal")
+
+func AgentCmd() *cobra.Command {
+	opts := struct {
+		SocketPath string
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "agent <resource | username@resource>...",
+		Short: "Run an ssh-agent that serves Pangolin certificates",
+		Long: `Run a local ssh-agent on a unix socket that serves just-in-time Pangolin certificates for the given resources.
+
+Each time an SSH client asks the agent for identities, certificates that are missing or about to expire are signed again. Keys are held in agent memory only and are never written to disk.
+
+Point any OpenSSH-based tool (git, ansible, rsync, VS Code Remote, ...) at the agent with SSH_AUTH_SOCK, or per host with the IdentityAgent option:
+
+  pangolin ssh agent my-server.internal &
+  export SSH_AUTH_SOCK=~/.config/pangolin/agent.sock
+  ssh <user>@<host>
+
+The agent runs in the foreground until interrupted.`,
+		Args: func(c *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errAgentResourceRequired
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				return err
+			}
+
+			socketPath := opts.SocketPath
+			if socketPath == "" {
+				dir, err := config.GetPangolinConfigDir()
+				if err != nil {
+					return err
+				}
+				socketPath = filepath.Join(dir, agentSocketName)
+			}
+
+			a := newCertAgent(apiClient, olm.ClientForOrg(orgID), orgID)
+			for _, arg := range args {
+				target := agentTarget{resource: arg}
+				if user, resource, hasAt := strings.Cut(arg, "@"); hasAt {
+					if resource == "" {
+						return errResourceIDRequired
+					}
+					target = agentTarget{username: user, resource: resource}
+				}
+				a.targets = append(a.targets, &target)
+			}
+
+			// Sign up front so misconfigured resources fail fast instead of
+			// surfacing as an opaque "Permission denied" in ssh.
+			a.refresh()
+			rows := make([][]string, 0, len(a.targets))
+			for _, t := range a.targets {
+				if t.signData == nil {
+					rows = append(rows, []string{t.resource, "-", "-", "not signed"})
+					continue
+				}
+				rows = append(rows, []string{t.resource, t.signData.User, t.signData.Hostname, t.expiresAt.Local().Format("Jan 2, 2006 15:04 MST")})
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			listener, err := listenAgentSocket(socketPath)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(socketPath)
+
+			logger.Success("SSH agent listening on %s", socketPath)
+			logger.Info("Use it with: export SSH_AUTH_SOCK=%s", socketPath)
+			fmt.Println()
+			utils.PrintTable([]string{"Resource", "User", "Host", "Expires"}, rows)
+
+			go func() {
+				<-ctx.Done()
+				listener.Close()
+			}()
+
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					if ctx.Err() != nil {
+						logger.Info("SSH agent stopped")
+						return nil
+					}
+					return fmt.Errorf("accept agent connection: %w", err)
+				}
+				go func() {
+					defer conn.Close()
+					if err := agent.ServeAgent(a, conn); err != nil && !errors.Is(err, io.EOF) {
+						logger.Debug("SSH agent connection ended: %v", err)
+					}
+				}()
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.SocketPath, "socket", "", "Path of the agent unix socket (default: ~/.config/pangolin/agent.sock)")
+
+	return cmd
+}
+
+// listenAgentSocket binds the agent socket, replacing a stale socket left
+// behind by an agent that did not shut down cleanly.
+func listenAgentSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("an agent is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	// Create the socket with owner-only permissions from the start.
+	oldMask := syscall.Umask(0o177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	return listener, nil
+}
+
+// agentTarget is a resource the agent serves a certificate for.
+type agentTarget struct {
+	resource string
+	username string
+
+	signer    ssh.Signer
+	signData  *api.SignSSHKeyData
+	expiresAt time.Time
+
+	// signing is set while a refresh signs the target; retryAt and
+	// failures back off after signing fails.
+	signing  bool
+	retryAt  time.Time
+	failures int
+}
+
+// signedCert is a freshly signed certificate for a target.
+type signedCert struct {
+	signer    ssh.Signer
+	signData  *api.SignSSHKeyData
+	expiresAt time.Time
+}
+
+// certAgent is an ssh agent whose identities are Pangolin certificates,
+// re-signed when they are about to expire. Keys added by clients (ssh-add)
+// are kept in a regular in-memory keyring alongside them.
+type certAgent struct {
+	apiClient *api.Client
+	olmClient *olm.Client
+	orgID     string
+	targets   []*agentTarget
+
+	mu      sync.Mutex
+	keyring agent.Agent
+	locked  bool
+}
+
+func newCertAgent(apiClient *api.Client, olmClient *olm.Client, orgID string) *certAgent {
+	return &certAgent{
+		apiClient: apiClient,
+		olmClient: olmClient,
+		orgID:     orgID,
+		keyring:   agent.NewKeyring(),
+	}
+}
+
+// refresh signs certificates for targets that have none or whose certificate
+// is inside the expiry safety margin. The targets are signed in parallel and
+// outside a.mu, so a slow target does not hold up other agent requests;
+// targets another refresh is already signing, or that failed recently, are
+// skipped.
+func (a *certAgent) refresh() {
+	now := time.Now()
+	a.mu.Lock()
+	var due []*agentTarget
+	for _, t := range a.targets {
+		if t.signing || now.Before(t.retryAt) {
+			continue
+		}
+		if t.signer != nil && now.Add(sshcerts.SafetyMargin).Before(t.expiresAt) {
+			continue
+		}
+		t.signing = true
+		due = append(due, t)
+	}
+	a.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, t := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cert, err := a.sign(t.resource, t.username)
+
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			t.signing = false
+			if err != nil {
+				t.failures++
+				delay := min(agentRetryMin<<min(t.failures-1, 5), agentRetryMax)
+				t.retryAt = time.Now().Add(delay)
+				logger.Warning("Failed to sign SSH certificate for %s (retrying in %s): %v", t.resource, delay, err)
+				return
+			}
+			t.signer, t.signData, t.expiresAt = cert.signer, cert.signData, cert.expiresAt
+			t.failures, t.retryAt = 0, time.Time{}
+		}()
+	}
+	wg.Wait()
+}
+
+// sign generates a key for resource and has it signed; it does not touch
+// the agent's state.
+func (a *certAgent) sign(resource, username string) (*signedCert, error) {
+	if a.olmClient.IsRunning() {
+		if _, err := a.olmClient.JITConnectByResourceID(resource); err != nil {
+			logger.Debug("JIT connect for %s: %v", resource, err)
+		}
+	}
+
+	signedAt := time.Now()
+	privPEM, _, cert, signData, err := GenerateAndSignKey(a.apiClient, a.orgID, resource, username)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ssh.ParseRawPrivateKey([]byte(privPEM))
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cert))
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	sshCert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("signed key is not a certificate")
+	}
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("load private key: %w", err)
+	}
+	certSigner, err := ssh.NewCertSigner(sshCert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("load certificate: %w", err)
+	}
+
+	expiresAt := sshcerts.ExpiresAt(signData, signedAt)
+	if expiresAt.IsZero() && sshCert.ValidBefore != ssh.CertTimeInfinity {
+		expiresAt = time.Unix(int64(sshCert.ValidBefore), 0)
+	}
+	logger.Debug("Signed SSH certificate for %s (expires %s)", resource, expiresAt.Format(time.RFC3339))
+	return &signedCert{signer: certSigner, signData: signData, expiresAt: expiresAt}, nil
+}
+
+func (a *certAgent) List() ([]*agent.Key, error) {
+	if a.isLocked() {
+		return nil, nil
+	}
+	a.refresh()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var keys []*agent.Key
+	for _, t := range a.targets {
+		if t.signer == nil {
+			continue
+		}
+		pub := t.signer.PublicKey()
+		keys = append(keys, &agent.Key{
+			Format:  pub.Type(),
+			Blob:    pub.Marshal(),
+			Comment: "pangolin:" + t.resource,
+		})
+	}
+
+	extra, err := a.keyring.List()
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, extra...), nil
+}
+
+func (a *certAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *certAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if a.isLocked() {
+		return nil, errors.New("agent is locked")
+	}
+	if signer := a.signerFor(key); signer != nil {
+		algorithm := ""
+		switch {
+		case flags&agent.SignatureFlagRsaSha256 != 0:
+			algorithm = ssh.KeyAlgoRSASHA256
+		case flags&agent.SignatureFlagRsaSha512 != 0:
+			algorithm = ssh.KeyAlgoRSASHA512
+		}
+		if algorithm != "" {
+			if as, ok := signer.(ssh.AlgorithmSigner); ok {
+				return as.SignWithAlgorithm(nil, data, algorithm)
+			}
+		}
+		return signer.Sign(nil, data)
+	}
+
+	if ext, ok := a.keyring.(agent.ExtendedAgent); ok {
+		return ext.SignWithFlags(key, data, flags)
+	}
+	return a.keyring.Sign(key, data)
+}
+
+func (a *certAgent) signerFor(key ssh.PublicKey) ssh.Signer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	blob := key.Marshal()
+	for _, t := range a.targets {
+		if t.signer != nil && string(t.signer.PublicKey().Marshal()) == string(blob) {
+			return t.signer
+		}
+	}
+	return nil
+}
+
+func (a *certAgent) Signers() ([]ssh.Signer, error) {
+	if a.isLocked() {
+		return nil, errors.New("agent is locked")
+	}
+
+	a.mu.Lock()
+	var signers []ssh.Signer
+	for _, t := range a.targets {
+		if t.signer != nil {
+			signers = append(signers, t.signer)
+		}
+	}
+	a.mu.Unlock()
+
+	extra, err := a.keyring.Signers()
+	if err != nil {
+		return nil, err
+	}
+	return append(signers, extra...), nil
+}
+
+func (a *certAgent) Add(key agent.AddedKey) error {
+	return a.keyring.Add(key)
+}
+
+func (a *certAgent) Remove(key ssh.PublicKey) error {
+	return a.keyring.Remove(key)
+}
+
+func (a *certAgent) RemoveAll() error {
+	return a.keyring.RemoveAll()
+}
+
+// Lock and Unlock delegate the passphrase check to the keyring and also
+// hide the Pangolin certificates while locked.
+func (a *certAgent) Lock(passphrase []byte) error {
+	if err := a.keyring.Lock(passphrase); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.locked = true
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *certAgent) Unlock(passphrase []byte) error {
+	if err := a.keyring.Unlock(passphrase); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.locked = false
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *certAgent) isLocked() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.locked
+}
+
+func (a *certAgent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
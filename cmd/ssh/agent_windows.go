@@ -0,0 +1,11 @@
+//go:build windows
+
+package ssh
+
+import "github.com/spf13/cobra"
+
+// AgentCmd returns nil on Windows; OpenSSH for Windows talks to agents over
+// named pipes rather than unix sockets.
+func AgentCmd() *cobra.Command {
+	return nil
+}
@@ -0,0 +1,126 @@
+package ssh
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/sshcerts"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// CertsCmd is the parent `ssh certs` command for the local certificate cache.
+func CertsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "certs",
+		Short: "Inspect and clear cached SSH certificates",
+		Long: `pangolin ssh and pangolin scp cache the signed key pair for each resource and reuse it until shortly before the certificate expires, which skips signing on repeated connections.
+
+Use these commands to see what is cached and to clear the cache (for example after access to a resource was revoked).`,
+	}
+	cmd.AddCommand(certsListCmd())
+	cmd.AddCommand(certsPurgeCmd())
+	return cmd
+}
+
+func certsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cached SSH certificates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := sshcerts.List()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				logger.Info("No cached SSH certificates")
+				return nil
+			}
+
+			now := time.Now()
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				user := e.Username
+				if user == "" && e.SignData != nil {
+					user = e.SignData.User
+				}
+				if user == "" {
+					user = "-"
+				}
+				host := "-"
+				keyID := "-"
+				if e.SignData != nil {
+					if e.SignData.Hostname != "" {
+						host = e.SignData.Hostname
+					}
+					if e.SignData.KeyID != "" {
+						keyID = e.SignData.KeyID
+					}
+				}
+				rows = append(rows, []string{
+					e.Resource,
+					user,
+					host,
+					keyID,
+					e.ExpiresAt.Local().Format("Jan 2, 2006 15:04 MST"),
+					certStatus(e, now),
+				})
+			}
+			utils.PrintTable([]string{"Resource", "User", "Host", "Key ID", "Expires", "Status"}, rows)
+			return nil
+		},
+	}
+}
+
+func certsPurgeCmd() *cobra.Command {
+	var expiredOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "purge [resource...]",
+		Short: "Remove cached SSH certificates",
+		Long:  `Removes cached SSH certificates. With no arguments every cached certificate is removed; pass resource aliases or identifiers to remove only those, or --expired to remove only certificates that can no longer be reused.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resources := make(map[string]bool, len(args))
+			for _, r := range args {
+				resources[r] = true
+			}
+
+			now := time.Now()
+			removed, err := sshcerts.Purge(func(e *sshcerts.Entry) bool {
+				if expiredOnly && e.Valid(now) {
+					return false
+				}
+				return len(resources) == 0 || resources[e.Resource]
+			})
+			if err != nil {
+				return err
+			}
+
+			switch removed {
+			case 0:
+				logger.Info("No cached SSH certificates to remove")
+			case 1:
+				logger.Success("Removed 1 cached SSH certificate")
+			default:
+				logger.Success("Removed %d cached SSH certificates", removed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&expiredOnly, "expired", false, "Only remove certificates that are expired or about to expire")
+
+	return cmd
+}
+
+func certStatus(e *sshcerts.Entry, now time.Time) string {
+	if e.Valid(now) {
+		return fmt.Sprintf("valid (%s left)", formatExpiresIn(int(e.ExpiresAt.Sub(now).Seconds())))
+	}
+	if now.Before(e.ExpiresAt) {
+		return "expiring"
+	}
+	return "expired"
+}
@@ -0,0 +1,307 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fosrl/cli/cmd/list"
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+const defaultExecParallel = 10
+
+var (
+	errExecCommandRequired = errors.New("a command is required after --; example: pangolin ssh exec --label web -- uptime")
+	errExecNoTargets       = errors.New("no resources to run on; pass resource aliases or --label")
+)
+
+// execResult is the outcome of running the command on one resource.
+type execResult struct {
+	Resource string `json:"resource"`
+	ExitCode int    `json:"exitCode"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"durationMs"`
+}
+
+// ExecCmd runs one command on many resources concurrently.
+func ExecCmd() *cobra.Command {
+	opts := struct {
+		Labels   []string
+		Parallel int
+		JSON     bool
+		Username string
+		Port     int
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "exec [resource...] [--label <label>] -- <command>",
+		Short: "Run a command on several resources in parallel",
+		Long: `Runs a command over SSH on every given resource and on every alias whose resource carries one of the --label labels. A certificate is signed for each resource just-in-time and the built-in SSH client runs the command, so no OpenSSH installation is needed.
+
+Output lines are prefixed with the resource they came from. The exit status is 0 when the command succeeded everywhere; otherwise it is the highest exit status seen (255 when a resource could not be reached). With --json nothing is streamed and one JSON array of per-resource results is printed at the end.
+
+Examples:
+  pangolin ssh exec --label web --parallel 10 -- uptime
+  pangolin ssh exec db-1.internal db-2.internal -- sudo systemctl restart postgresql
+  pangolin ssh exec --label web --json -- cat /etc/os-release`,
+		RunE: func(c *cobra.Command, args []string) error {
+			dash := c.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return errExecCommandRequired
+			}
+			resources, command := args[:dash], strings.Join(args[dash:], " ")
+			if opts.Parallel < 1 {
+				opts.Parallel = 1
+			}
+
+			// stdout carries the JSON document; route logging to stderr.
+			stdout := os.Stdout
+			if opts.JSON {
+				os.Stdout = os.Stderr
+				defer func() { os.Stdout = stdout }()
+			}
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			targets, err := execTargets(apiClient, accountStore, resources, opts.Labels)
+			if err != nil {
+				return err
+			}
+
+			var out *prefixedOutput
+			if !opts.JSON {
+				out = newPrefixedOutput(targets)
+			}
+
+			results := make([]execResult, len(targets))
+			sem := make(chan struct{}, opts.Parallel)
+			var wg sync.WaitGroup
+			for i, resourceID := range targets {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					results[i] = runExecOn(apiClient, accountStore, resourceID, opts.Username, opts.Port, command, out)
+				}()
+			}
+			wg.Wait()
+
+			exitCode := 0
+			for _, r := range results {
+				exitCode = max(exitCode, r.ExitCode)
+			}
+
+			if opts.JSON {
+				enc := json.NewEncoder(stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				printExecSummary(results)
+			}
+
+			if exitCode != 0 {
+				os.Exit(exitCode)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&opts.Labels, "label", "l", nil, "Run on aliases for resources with this label (repeatable, OR)")
+	cmd.Flags().IntVarP(&opts.Parallel, "parallel", "P", defaultExecParallel, "Maximum number of resources to run on at once")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print per-resource results as JSON instead of streaming output")
+	cmd.Flags().StringVarP(&opts.Username, "user", "u", "", "Remote username (default: the resource's default user)")
+	cmd.Flags().IntVarP(&opts.Port, "port", "p", 0, "Remote SSH port (default: 22)")
+
+	return cmd
+}
+
+// execTargets returns the explicit resources plus the aliases matching
+// labels, without duplicates, in a stable order.
+func execTargets(apiClient *api.Client, accountStore *config.AccountStore, resources, labels []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(r string) {
+		if r != "" && !seen[r] {
+			seen[r] = true
+			targets = append(targets, r)
+		}
+	}
+	for _, r := range resources {
+		add(r)
+	}
+
+	if len(labels) > 0 {
+		orgID, err := utils.ResolveOrgID(accountStore, "")
+		if err != nil {
+			return nil, err
+		}
+		aliases, err := list.FetchAliases(apiClient, orgID, labels)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(aliases)
+		for _, a := range aliases {
+			add(a)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, errExecNoTargets
+	}
+	return targets, nil
+}
+
+// runExecOn prepares resourceID and runs command on it. With out set, output
+// is streamed with a prefix; otherwise it is captured in the result.
+func runExecOn(apiClient *api.Client, accountStore *config.AccountStore, resourceID, username string, port int, command string, out *prefixedOutput) execResult {
+	start := time.Now()
+	result := execResult{Resource: resourceID}
+	fail := func(err error) execResult {
+		result.ExitCode = 255
+		result.Error = err.Error()
+		result.Duration = time.Since(start).Milliseconds()
+		if out != nil {
+			out.line(resourceID, os.Stderr, "error: "+err.Error())
+		}
+		return result
+	}
+
+	target, err := prepareTarget(apiClient, accountStore, resourceID, username, waitForSiteConnectionQuiet)
+	if err != nil {
+		return fail(err)
+	}
+
+	addr, err := NativeSSHAddress(target.SignData.Hostname, port)
+	if err != nil {
+		return fail(err)
+	}
+	config, err := NativeSSHClientConfig(RunOpts{
+		User:           target.SignData.User,
+		PrivateKeyPEM:  target.PrivateKeyPEM,
+		Certificate:    target.Certificate,
+		KnownHostsFile: target.KnownHostsFile,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	config.Timeout = siteConnectTimeout
+
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return fail(fmt.Errorf("ssh dial: %w", err))
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fail(fmt.Errorf("ssh session: %w", err))
+	}
+	defer session.Close()
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	if out != nil {
+		stdoutW, stderrW := out.writer(resourceID, os.Stdout), out.writer(resourceID, os.Stderr)
+		defer stdoutW.Flush()
+		defer stderrW.Flush()
+		session.Stdout, session.Stderr = stdoutW, stderrW
+	} else {
+		session.Stdout, session.Stderr = &stdoutBuf, &stderrBuf
+	}
+
+	code, err := nativeExitCode(session.Run(command))
+	if err != nil {
+		return fail(err)
+	}
+	result.ExitCode = code
+	result.Stdout = stdoutBuf.String()
+	result.Stderr = stderrBuf.String()
+	result.Duration = time.Since(start).Milliseconds()
+	return result
+}
+
+func printExecSummary(results []execResult) {
+	var failed []string
+	for _, r := range results {
+		if r.ExitCode != 0 {
+			failed = append(failed, fmt.Sprintf("%s (exit %d)", r.Resource, r.ExitCode))
+		}
+	}
+	if len(failed) == 0 {
+		logger.Success("Command succeeded on %d resources", len(results))
+		return
+	}
+	logger.Warning("Command failed on %d of %d resources: %s", len(failed), len(results), strings.Join(failed, ", "))
+}
+
+// prefixedOutput writes whole lines from several resources to the terminal,
+// each prefixed with its resource name padded to a common width.
+type prefixedOutput struct {
+	mu    sync.Mutex
+	width int
+}
+
+func newPrefixedOutput(targets []string) *prefixedOutput {
+	width := 0
+	for _, t := range targets {
+		width = max(width, len(t))
+	}
+	return &prefixedOutput{width: width}
+}
+
+func (p *prefixedOutput) line(resource string, w io.Writer, text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(w, "%-*s | %s\n", p.width, resource, text)
+}
+
+func (p *prefixedOutput) writer(resource string, w io.Writer) *prefixedWriter {
+	return &prefixedWriter{out: p, resource: resource, w: w}
+}
+
+// prefixedWriter buffers a partial line until its newline arrives.
+type prefixedWriter struct {
+	out      *prefixedOutput
+	resource string
+	w        io.Writer
+	buf      []byte
+}
+
+func (pw *prefixedWriter) Write(b []byte) (int, error) {
+	pw.buf = append(pw.buf, b...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		pw.out.line(pw.resource, pw.w, strings.TrimSuffix(string(pw.buf[:i]), "\r"))
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes a trailing line that had no newline.
+func (pw *prefixedWriter) Flush() {
+	if len(pw.buf) > 0 {
+		pw.out.line(pw.resource, pw.w, string(pw.buf))
+		pw.buf = nil
+	}
+}
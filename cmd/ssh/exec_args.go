@@ -5,7 +5,7 @@ import "strconv"
 // buildExecSSHArgs assembles argv for the system ssh(1) binary:
 //
 //	ssh <identity: -l -i -o Certificate -p> <user OpenSSH options> <hostname> <remote command>...
-func buildExecSSHArgs(sshPath, user, hostname string, port int, keyPath, certPath string, pass SSHPassthrough) []string {
+func buildExecSSHArgs(sshPath, user, hostname string, port int, keyPath, certPath, knownHostsFile string, strictHostKeys bool, pass SSHPassthrough) []string {
 	args := []string{sshPath}
 	if user != "" {
 		args = append(args, "-l", user)
@@ -25,10 +25,7 @@ func buildExecSSHArgs(sshPath, user, hostname string, port int, keyPath, certPat
 		"-o", "PasswordAuthentication=yes",
 		"-o", "KbdInteractiveAuthentication=yes",
 	)
-	// The built-in SSH server generates a fresh ephemeral host key on every
-	// restart, so skip known_hosts checking to avoid spurious MITM warnings.
-	// LogLevel=ERROR suppresses the "Permanently added ..." informational line.
-	args = append(args, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "-o", "LogLevel=ERROR")
+	args = append(args, HostKeyCheckingOptions(knownHostsFile, strictHostKeys)...)
 	if port > 0 {
 		args = append(args, "-p", strconv.Itoa(port))
 	}
@@ -37,3 +34,37 @@ func buildExecSSHArgs(sshPath, user, hostname string, port int, keyPath, certPat
 	args = append(args, pass.RemoteCommand...)
 	return args
 }
+
+// HostKeyCheckingOptions returns the ssh(1) -o options for host key checking.
+// With a known_hosts file, unknown hosts are pinned on first use and changed
+// keys are refused; with strict set, only keys the file already vouches for
+// are accepted (see KnownHostsFile). Without one (a native SSH server that
+// offers no host certificate) checking is skipped. LogLevel=ERROR suppresses
+// the "Permanently added ..." informational line.
+func HostKeyCheckingOptions(knownHostsFile string, strict bool) []string {
+	if knownHostsFile == "" {
+		return []string{"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "-o", "LogLevel=ERROR"}
+	}
+	mode := "accept-new"
+	if strict {
+		mode = "yes"
+	}
+	return []string{"-o", "StrictHostKeyChecking=" + mode, "-o", "UserKnownHostsFile=" + knownHostsFile, "-o", "LogLevel=ERROR"}
+}
+
+// JITIdentityOptions returns the ssh(1) options that authenticate with only
+// the JIT key and certificate at keyPath and certPath, for non-interactive
+// OpenSSH-family clients such as sftp(1) and rsync(1).
+func JITIdentityOptions(keyPath, certPath string) []string {
+	args := []string{"-i", keyPath}
+	if certPath != "" {
+		args = append(args, "-o", "CertificateFile="+certPath)
+	}
+	return append(args,
+		"-o", "PubkeyAuthentication=yes",
+		"-o", "PreferredAuthentications=publickey",
+		"-o", "IdentitiesOnly=yes",
+		"-o", "PasswordAuthentication=no",
+		"-o", "KbdInteractiveAuthentication=no",
+	)
+}
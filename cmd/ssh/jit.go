@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/sshcerts"
 	"github.com/fosrl/cli/internal/sshkeys"
 )
 
@@ -65,3 +68,45 @@ func GenerateAndSignKey(client *api.Client, orgID string, resourceID string, use
 
 	return "", "", "", nil, fmt.Errorf("SSH error: timed out waiting for round-trip message")
 }
+
+// SignKeyCached returns a key pair and certificate for the resource, reusing
+// a cached pair from a previous call while its certificate is still valid
+// and otherwise signing a fresh one via GenerateAndSignKey.
+func SignKeyCached(client *api.Client, accountStore *config.AccountStore, orgID string, resourceID string, username string) (privPEM, pubKey, cert string, signData *api.SignSSHKeyData, err error) {
+	key := sshcerts.Key{
+		OrgID:    orgID,
+		Resource: resourceID,
+		Username: username,
+	}
+	if accountStore != nil {
+		key.UserID = accountStore.ActiveUserID
+	}
+
+	entry, err := sshcerts.Lookup(key)
+	if err != nil {
+		logger.Debug("Failed to read SSH certificate cache: %v", err)
+	}
+	if entry != nil && entry.SignData != nil {
+		logger.Debug("Reusing cached SSH certificate for %s (expires %s)", resourceID, entry.ExpiresAt.Format(time.RFC3339))
+		return entry.PrivateKeyPEM, entry.PublicKey, entry.Certificate, entry.SignData, nil
+	}
+
+	signedAt := time.Now()
+	privPEM, pubKey, cert, signData, err = GenerateAndSignKey(client, orgID, resourceID, username)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	if err := sshcerts.Store(key, &sshcerts.Entry{
+		PrivateKeyPEM: privPEM,
+		PublicKey:     pubKey,
+		Certificate:   cert,
+		SignData:      signData,
+		CreatedAt:     signedAt,
+		ExpiresAt:     sshcerts.ExpiresAt(signData, signedAt),
+	}); err != nil {
+		logger.Debug("Failed to cache SSH certificate: %v", err)
+	}
+
+	return privPEM, pubKey, cert, signData, nil
+}
@@ -0,0 +1,255 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/sshknownhosts"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+const hostKeyScanTimeout = 15 * time.Second
+
+var errHostKeyCaptured = errors.New("host key captured")
+
+// KnownHostsFile returns the org's known_hosts file to verify the host in
+// signData against.
+//
+// The native SSH server generates a fresh host key on every restart, so its
+// plain key cannot be pinned. When it presents a host certificate, the host
+// is checked here (port 0 meaning 22): the signing CA is pinned as a
+// @cert-authority entry on first use and a certificate from any other CA is
+// refused. Only when the server offers no certificate, and none was pinned
+// for it before, is "" returned, and host key checking is skipped with a
+// warning.
+func KnownHostsFile(orgID string, signData *api.SignSSHKeyData, port int) (string, error) {
+	path, err := sshknownhosts.Ensure(orgID)
+	if err != nil {
+		return "", err
+	}
+	if signData == nil || signData.AuthDaemonMode != "native" {
+		return path, nil
+	}
+
+	addr, err := NativeSSHAddress(signData.Hostname, port)
+	if err != nil {
+		return "", err
+	}
+	key, remote, err := scanHostKey(addr)
+	if err != nil {
+		return "", err
+	}
+	if cert, ok := key.(*ssh.Certificate); !ok || cert.CertType != ssh.HostCert {
+		pinned, err := sshknownhosts.HasEntries(path, addr)
+		if err != nil {
+			return "", err
+		}
+		if pinned {
+			return "", fmt.Errorf("host key verification failed for %s: it presented a plain host key, but a host CA is pinned for it\nIf the change is expected, run: pangolin ssh known-hosts remove %s", signData.Hostname, signData.Hostname)
+		}
+		logger.Warning("WARNING: %s offers no host certificate, so its identity CANNOT be verified and the connection is open to interception. Configure a host certificate on the server to enable host verification.", signData.Hostname)
+		return "", nil
+	}
+	if err := sshknownhosts.Callback(path)(addr, remote, key); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// KnownHostsCmd is the parent `ssh known-hosts` command.
+func KnownHostsCmd() *cobra.Command {
+	var orgID string
+
+	cmd := &cobra.Command{
+		Use:   "known-hosts",
+		Short: "Manage pinned SSH host keys",
+		Long: `pangolin ssh and pangolin scp pin each host's key in a per-organization known_hosts file the first time they connect (trust on first use). Hosts that present a host certificate have their signing CA pinned as a @cert-authority entry instead. A host whose key later changes is refused.
+
+Use these commands to inspect the pinned keys, remove entries, or pin a host's current key again after it was legitimately replaced.`,
+	}
+	cmd.PersistentFlags().StringVar(&orgID, "org", "", "Organization ID (default: selected organization)")
+
+	cmd.AddCommand(knownHostsListCmd(&orgID))
+	cmd.AddCommand(knownHostsRemoveCmd(&orgID))
+	cmd.AddCommand(knownHostsRepinCmd(&orgID))
+	return cmd
+}
+
+func knownHostsPath(c *cobra.Command, flagOrgID string) (string, error) {
+	accountStore := config.AccountStoreFromContext(c.Context())
+	orgID, err := utils.ResolveOrgID(accountStore, flagOrgID)
+	if err != nil {
+		return "", err
+	}
+	return sshknownhosts.Path(orgID)
+}
+
+func knownHostsListCmd(orgID *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List pinned host keys and host CAs",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			path, err := knownHostsPath(c, *orgID)
+			if err != nil {
+				return err
+			}
+			entries, err := sshknownhosts.Read(path)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				logger.Info("No pinned host keys in %s", path)
+				return nil
+			}
+
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				kind := e.Key.Type()
+				switch e.Marker {
+				case "cert-authority":
+					kind = "CA " + kind
+				case "revoked":
+					kind = "revoked " + kind
+				}
+				rows = append(rows, []string{strings.Join(e.Hosts, ","), kind, e.Fingerprint()})
+			}
+			utils.PrintTable([]string{"Host", "Type", "Fingerprint"}, rows)
+			return nil
+		},
+	}
+}
+
+func knownHostsRemoveCmd(orgID *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <host>...",
+		Short: "Remove pinned entries for hosts",
+		Long:  `Removes every known_hosts entry for the given hosts. The next connection pins the key the host presents then.`,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			path, err := knownHostsPath(c, *orgID)
+			if err != nil {
+				return err
+			}
+			for _, host := range args {
+				removed, err := sshknownhosts.Remove(path, host)
+				if err != nil {
+					return err
+				}
+				if removed == 0 {
+					logger.Info("No entries for %s", host)
+					continue
+				}
+				logger.Success("Removed %d entries for %s", removed, host)
+			}
+			return nil
+		},
+	}
+}
+
+func knownHostsRepinCmd(orgID *string) *cobra.Command {
+	var port int
+
+	cmd := &cobra.Command{
+		Use:   "repin <resource alias or identifier>",
+		Short: "Pin the key a resource's SSH host presents now",
+		Long:  `Connects to the resource's SSH host, reads the host key it presents, and replaces any pinned entries for that host with it. Use this after a host was reinstalled or its key was rotated; compare the printed fingerprint with the host before trusting it.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			resourceID := args[0]
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			resolvedOrgID, err := utils.ResolveOrgID(accountStore, *orgID)
+			if err != nil {
+				return err
+			}
+
+			client := olm.ClientForOrg(resolvedOrgID)
+			if !client.IsRunning() {
+				return errNoClientRunning
+			}
+
+			if _, err := client.JITConnectByResourceID(resourceID); err != nil {
+				logger.Warning("%v", err)
+			}
+
+			_, _, _, signData, err := SignKeyCached(apiClient, accountStore, resolvedOrgID, resourceID, "")
+			if err != nil {
+				return err
+			}
+			if signData == nil || signData.Hostname == "" {
+				return errHostnameRequired
+			}
+
+			if siteIDs := signDataSiteIDs(signData); len(siteIDs) > 0 {
+				if err := waitForAnySiteConnection(client, siteIDs); err != nil {
+					return err
+				}
+			}
+
+			addr, err := NativeSSHAddress(signData.Hostname, port)
+			if err != nil {
+				return err
+			}
+			key, _, err := scanHostKey(addr)
+			if err != nil {
+				return err
+			}
+			cert, isCert := key.(*ssh.Certificate)
+			isCert = isCert && cert.CertType == ssh.HostCert
+			if signData.AuthDaemonMode == "native" && !isCert {
+				return fmt.Errorf("%s uses the native SSH server and offers no host certificate; its host key changes on every restart and is not pinned", resourceID)
+			}
+
+			path, err := sshknownhosts.Ensure(resolvedOrgID)
+			if err != nil {
+				return err
+			}
+			if err := sshknownhosts.Pin(path, addr, key); err != nil {
+				return err
+			}
+
+			if isCert {
+				logger.Success("Pinned host CA %s for %s", ssh.FingerprintSHA256(cert.SignatureKey), signData.Hostname)
+			} else {
+				logger.Success("Pinned host key %s %s for %s", key.Type(), ssh.FingerprintSHA256(key), signData.Hostname)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVarP(&port, "port", "p", 0, "Remote SSH port (default: 22)")
+
+	return cmd
+}
+
+// scanHostKey performs an SSH handshake with addr only far enough to read the
+// host key it presents, and the remote address it was read from.
+func scanHostKey(addr string) (ssh.PublicKey, net.Addr, error) {
+	var key ssh.PublicKey
+	var remote net.Addr
+	cfg := &ssh.ClientConfig{
+		User: "pangolin",
+		HostKeyCallback: func(_ string, r net.Addr, k ssh.PublicKey) error {
+			key, remote = k, r
+			return errHostKeyCaptured
+		},
+		Timeout: hostKeyScanTimeout,
+	}
+	_, err := ssh.Dial("tcp", addr, cfg)
+	if key == nil {
+		return nil, nil, fmt.Errorf("read host key from %s: %w", addr, err)
+	}
+	return key, remote, nil
+}
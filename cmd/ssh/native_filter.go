@@ -116,24 +116,74 @@ func FilterForNativeSCPMode(pt SSHPassthrough) (SSHPassthrough, []string) {
 	return out, stripped
 }
 
+// FilterForNativeSFTPMode strips sftp(1) options that are unsafe or
+// meaningless against the native SSH server and returns the safe subset and
+// rejected tokens.
+//
+// Allowed sftp flags: -a (resume), -C (compression), -f (fsync), -p
+// (preserve), -q (quiet), -r (recursive), -v/-vv/… (verbosity), -B <size>,
+// -b <batchfile>, -c <cipher>, -l <limit>, -R <requests>.
+// Blocked: -o, -F, -J, -S, -D, -s, and anything unknown.
+func FilterForNativeSFTPMode(pt SSHPassthrough) (SSHPassthrough, []string) {
+	var allowed []string
+	var stripped []string
+
+	opts := pt.Options
+	for i := 0; i < len(opts); i++ {
+		tok := opts[i]
+		if tok == "--" {
+			break
+		}
+
+		group := opts[i:min(i+1+sftpOptionExtras(tok), len(opts))]
+		i += len(group) - 1
+		if sftpNativeAllowedOption(tok) {
+			allowed = append(allowed, group...)
+		} else {
+			stripped = append(stripped, group...)
+		}
+	}
+
+	var out SSHPassthrough
+	if len(allowed) > 0 {
+		out.Options = allowed
+	}
+	// The destination operand is in RemoteCommand — always pass through.
+	out.RemoteCommand = pt.RemoteCommand
+	return out, stripped
+}
+
+const (
+	// sftpNativeBoolFlags may be combined into one token.
+	sftpNativeBoolFlags  = "aCfpqrv"
+	sftpNativeValueFlags = "BbclR"
+)
+
+// sftpNativeAllowedOption reports whether an sftp(1) flag is safe for the
+// native server.
+func sftpNativeAllowedOption(tok string) bool {
+	if len(tok) < 2 || tok[0] != '-' || tok[1] == '-' {
+		return false
+	}
+	if strings.Trim(tok[1:], sftpNativeBoolFlags) == "" {
+		return true
+	}
+	return len(tok) == 2 && strings.IndexByte(sftpNativeValueFlags, tok[1]) >= 0
+}
+
+// scpNativeBoolFlags are the boolean scp(1) flags allowed against the native
+// server; they may be combined into one token.
+const scpNativeBoolFlags = "rRpqvCB3"
+
 // scpNativeAllowedOption reports whether a scp(1) flag is safe for the native server.
 func scpNativeAllowedOption(tok string) (allowed bool, consumesNext bool) {
 	if tok == "" || tok == "--" || !strings.HasPrefix(tok, "-") || tok == "-" {
 		return false, false
 	}
 
-	// -v, -vv, -vvv, … — verbosity is client-side only.
-	if len(tok) >= 2 {
-		allV := true
-		for _, c := range tok[1:] {
-			if c != 'v' {
-				allV = false
-				break
-			}
-		}
-		if allV {
-			return true, false
-		}
+	// -v, -vv, -vvv, … and combined boolean flags such as -rp.
+	if len(tok) >= 2 && !strings.HasPrefix(tok, "--") && strings.Trim(tok[1:], scpNativeBoolFlags) == "" {
+		return true, false
 	}
 
 	switch tok {
@@ -0,0 +1,296 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/socks5"
+	"golang.org/x/crypto/ssh"
+)
+
+// nativeOptions is the subset of ssh(1) pass-through options the built-in
+// client implements.
+type nativeOptions struct {
+	LocalForwards   []forwardSpec
+	RemoteForwards  []forwardSpec
+	DynamicForwards []forwardSpec
+	NoCommand       bool // -N
+	ForceTTY        bool // -t
+	DisableTTY      bool // -T
+	Command         []string
+
+	// Unsupported lists options that were ignored.
+	Unsupported []string
+}
+
+// forwardSpec is one -L, -R, or -D argument. For -D only the bind side is set.
+type forwardSpec struct {
+	BindHost string
+	BindPort int
+	Host     string
+	HostPort int
+}
+
+func (f forwardSpec) bindAddr(defaultHost string) string {
+	host := f.BindHost
+	switch host {
+	case "":
+		host = defaultHost
+	case "*":
+		host = ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(f.BindPort))
+}
+
+func (f forwardSpec) targetAddr() string {
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.HostPort))
+}
+
+// parseNativeOptions interprets pass-through ssh(1) arguments for the
+// built-in client. Options it cannot honor are collected in Unsupported.
+func parseNativeOptions(pt SSHPassthrough) (nativeOptions, error) {
+	out := nativeOptions{Command: pt.RemoteCommand}
+
+	opts := pt.Options
+	for i := 0; i < len(opts); i++ {
+		tok := opts[i]
+		if tok == "--" {
+			continue
+		}
+
+		// value returns the option's argument, either attached (-L8080:...)
+		// or as the following token.
+		value := func() (string, bool) {
+			if len(tok) > 2 {
+				return tok[2:], true
+			}
+			if i+1 < len(opts) {
+				i++
+				return opts[i], true
+			}
+			return "", false
+		}
+
+		if len(tok) < 2 || tok[0] != '-' {
+			out.Unsupported = append(out.Unsupported, tok)
+			continue
+		}
+
+		switch tok[1] {
+		case 'L', 'R':
+			spec, ok := value()
+			if !ok {
+				return out, fmt.Errorf("%s requires an argument", tok[:2])
+			}
+			f, err := parseForwardSpec(spec)
+			if err != nil {
+				return out, fmt.Errorf("bad %s forward %q: %w", tok[:2], spec, err)
+			}
+			if tok[1] == 'L' {
+				out.LocalForwards = append(out.LocalForwards, f)
+			} else {
+				out.RemoteForwards = append(out.RemoteForwards, f)
+			}
+		case 'D':
+			spec, ok := value()
+			if !ok {
+				return out, fmt.Errorf("-D requires an argument")
+			}
+			f, err := parseDynamicSpec(spec)
+			if err != nil {
+				return out, fmt.Errorf("bad -D forward %q: %w", spec, err)
+			}
+			out.DynamicForwards = append(out.DynamicForwards, f)
+		default:
+			// Combined boolean flags such as -Nt or -qT.
+			handled := true
+			for _, c := range tok[1:] {
+				switch c {
+				case 'N':
+					out.NoCommand = true
+				case 't':
+					out.ForceTTY = true
+				case 'T':
+					out.DisableTTY = true
+				case 'q', 'v', 'C':
+					// Presentation/transport hints with no effect here.
+				default:
+					handled = false
+				}
+			}
+			if !handled {
+				out.Unsupported = append(out.Unsupported, tok)
+				for n := openSSHOptionExtras(tok, opts, i); n > 0 && i+1 < len(opts); n-- {
+					i++
+					out.Unsupported = append(out.Unsupported, opts[i])
+				}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// parseForwardSpec parses [bind_address:]port:host:hostport.
+func parseForwardSpec(spec string) (forwardSpec, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return forwardSpec{}, err
+	}
+	var f forwardSpec
+	switch len(parts) {
+	case 3:
+		f.Host = parts[1]
+		if f.BindPort, err = parsePort(parts[0]); err != nil {
+			return f, err
+		}
+		if f.HostPort, err = parsePort(parts[2]); err != nil {
+			return f, err
+		}
+	case 4:
+		f.BindHost = parts[0]
+		f.Host = parts[2]
+		if f.BindPort, err = parsePort(parts[1]); err != nil {
+			return f, err
+		}
+		if f.HostPort, err = parsePort(parts[3]); err != nil {
+			return f, err
+		}
+	default:
+		return f, fmt.Errorf("expected [bind_address:]port:host:hostport")
+	}
+	if f.Host == "" {
+		return f, fmt.Errorf("missing host")
+	}
+	return f, nil
+}
+
+// parseDynamicSpec parses [bind_address:]port.
+func parseDynamicSpec(spec string) (forwardSpec, error) {
+	parts, err := splitForwardSpec(spec)
+	if err != nil {
+		return forwardSpec{}, err
+	}
+	var f forwardSpec
+	switch len(parts) {
+	case 1:
+		f.BindPort, err = parsePort(parts[0])
+	case 2:
+		f.BindHost = parts[0]
+		f.BindPort, err = parsePort(parts[1])
+	default:
+		err = fmt.Errorf("expected [bind_address:]port")
+	}
+	return f, err
+}
+
+// splitForwardSpec splits on ':' while keeping bracketed IPv6 addresses
+// intact (and unbracketing them).
+func splitForwardSpec(spec string) ([]string, error) {
+	var parts []string
+	for len(spec) > 0 {
+		if spec[0] == '[' {
+			end := strings.IndexByte(spec, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '['")
+			}
+			parts = append(parts, spec[1:end])
+			spec = spec[end+1:]
+			if spec != "" {
+				if spec[0] != ':' {
+					return nil, fmt.Errorf("expected ':' after ']'")
+				}
+				spec = spec[1:]
+			}
+			continue
+		}
+		part, rest, found := strings.Cut(spec, ":")
+		parts = append(parts, part)
+		spec = rest
+		if found && rest == "" {
+			parts = append(parts, "")
+		}
+	}
+	return parts, nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// startForwards sets up all requested forwards on client. Listeners are
+// closed when the returned function is called.
+func startForwards(client *ssh.Client, opts nativeOptions) (func(), error) {
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	for _, f := range opts.LocalForwards {
+		l, err := net.Listen("tcp", f.bindAddr("localhost"))
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("local forward %s: %w", f.bindAddr("localhost"), err)
+		}
+		listeners = append(listeners, l)
+		go acceptForward(l, func() (net.Conn, error) {
+			return client.Dial("tcp", f.targetAddr())
+		})
+	}
+
+	for _, f := range opts.RemoteForwards {
+		l, err := client.Listen("tcp", f.bindAddr("localhost"))
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("remote forward %s: %w", f.bindAddr("localhost"), err)
+		}
+		listeners = append(listeners, l)
+		go acceptForward(l, func() (net.Conn, error) {
+			return net.Dial("tcp", f.targetAddr())
+		})
+	}
+
+	for _, f := range opts.DynamicForwards {
+		l, err := net.Listen("tcp", f.bindAddr("localhost"))
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("dynamic forward %s: %w", f.bindAddr("localhost"), err)
+		}
+		listeners = append(listeners, l)
+		go func() {
+			_ = socks5.Serve(l, func(_ context.Context, network, addr string) (net.Conn, error) {
+				return client.Dial(network, addr)
+			})
+		}()
+	}
+
+	return closeAll, nil
+}
+
+func acceptForward(l net.Listener, dial func() (net.Conn, error)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			upstream, err := dial()
+			if err != nil {
+				logger.Debug("Forward from %s failed: %v", l.Addr(), err)
+				conn.Close()
+				return
+			}
+			socks5.Pipe(conn, upstream)
+		}()
+	}
+}
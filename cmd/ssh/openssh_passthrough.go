@@ -35,6 +35,42 @@ func ParseOpenSSHPassThrough(args []string) SSHPassthrough {
 	return SSHPassthrough{Options: opts, RemoteCommand: cloneStringSliceOrNil(args[i:])}
 }
 
+// ParseSFTPPassThrough splits sftp(1) arguments into options and the
+// destination operand (in RemoteCommand). sftp's flags differ from ssh's
+// (-p preserves times, -s names a subsystem), so ParseOpenSSHPassThrough does
+// not apply.
+func ParseSFTPPassThrough(args []string) SSHPassthrough {
+	var opts []string
+	i := 0
+	for i < len(args) {
+		a := args[i]
+		if a == "--" {
+			opts = append(opts, a)
+			i++
+			break
+		}
+		if !strings.HasPrefix(a, "-") || a == "-" {
+			break
+		}
+		end := i + 1 + sftpOptionExtras(a)
+		if end > len(args) {
+			end = len(args)
+		}
+		opts = append(opts, args[i:end]...)
+		i = end
+	}
+	return SSHPassthrough{Options: opts, RemoteCommand: cloneStringSliceOrNil(args[i:])}
+}
+
+// sftpOptionExtras returns how many following args an sftp(1) option
+// consumes.
+func sftpOptionExtras(a string) int {
+	if len(a) == 2 && a[0] == '-' && strings.IndexByte("BbcDFiJloPRSsX", a[1]) >= 0 {
+		return 1
+	}
+	return 0
+}
+
 func cloneStringSliceOrNil(s []string) []string {
 	if len(s) == 0 {
 		return nil
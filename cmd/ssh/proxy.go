@@ -0,0 +1,115 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var errInvalidPort = errors.New("port must be a number between 1 and 65535")
+
+func ProxyCmd() *cobra.Command {
+	var stdout *os.File
+	cmd := &cobra.Command{
+		Use:   "proxy <resource alias or identifier> <port>",
+		Short: "Connect stdin/stdout to a resource's SSH port (for ProxyCommand)",
+		Long: `Connects to the resource, then copies stdin to the remote port and the remote port to stdout. Nothing else is written to stdout, so this can be used as an OpenSSH ProxyCommand for tools that run ssh themselves (Ansible, Terraform provisioners, mosh, ...).
+
+Example ~/.ssh/config entry:
+
+  Host my-server.internal
+      ProxyCommand pangolin ssh proxy %n %p`,
+		Args: cobra.ExactArgs(2),
+		// stdout carries the proxied stream; route all logging to stderr
+		// before the root command refreshes credentials or prints anything.
+		PersistentPreRunE: func(c *cobra.Command, args []string) error {
+			stdout = os.Stdout
+			os.Stdout = os.Stderr
+			if root := c.Root(); root.PersistentPreRunE != nil {
+				return root.PersistentPreRunE(c, args)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			defer func() { os.Stdout = stdout }()
+
+			resourceID := args[0]
+			port, err := strconv.Atoi(args[1])
+			if err != nil || port < 1 || port > 65535 {
+				return errInvalidPort
+			}
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				return err
+			}
+
+			client := olm.ClientForOrg(orgID)
+			if !client.IsRunning() {
+				return errNoClientRunning
+			}
+
+			if _, err := client.JITConnectByResourceID(resourceID); err != nil {
+				logger.Debug("JIT connect for %s: %v", resourceID, err)
+			}
+
+			_, _, _, signData, err := SignKeyCached(apiClient, accountStore, orgID, resourceID, "")
+			if err != nil {
+				return err
+			}
+			if signData == nil || signData.Hostname == "" {
+				return errHostnameRequired
+			}
+
+			// The interactive spinner would read from stdin, which belongs
+			// to the SSH client here.
+			if siteIDs := signDataSiteIDs(signData); len(siteIDs) > 0 {
+				if err := waitForSiteConnectionQuiet(client, siteIDs); err != nil {
+					return err
+				}
+			}
+
+			conn, err := net.Dial("tcp", net.JoinHostPort(signData.Hostname, strconv.Itoa(port)))
+			if err != nil {
+				return fmt.Errorf("connect to %s: %w", signData.Hostname, err)
+			}
+			defer conn.Close()
+
+			return spliceStdio(conn, os.Stdin, stdout)
+		},
+	}
+
+	return cmd
+}
+
+// spliceStdio copies in to conn and conn to out until the remote side closes
+// the connection. EOF on in half-closes the connection so the remote sees it.
+func spliceStdio(conn net.Conn, in io.Reader, out io.Writer) error {
+	go func() {
+		_, _ = io.Copy(conn, in)
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			_ = tcp.CloseWrite()
+		}
+	}()
+
+	_, err := io.Copy(out, conn)
+	// Remote closed: stop waiting on stdin, which may never reach EOF.
+	conn.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
@@ -77,7 +77,7 @@ func RunExec(opts RunOpts) (int, error) {
 		defer cleanup()
 	}
 
-	argv := buildExecSSHArgs(sshPath, opts.User, opts.Hostname, opts.Port, keyPath, certPath, opts.SSHPassthrough)
+	argv := buildExecSSHArgs(sshPath, opts.User, opts.Hostname, opts.Port, keyPath, certPath, opts.KnownHostsFile, opts.StrictHostKeys, opts.SSHPassthrough)
 	cmd := exec.Command(argv[0], argv[1:]...)
 
 	usePTY := isatty.IsTerminal(os.Stdin.Fd())
@@ -88,6 +88,18 @@ func RunExec(opts RunOpts) (int, error) {
 	return runExecWithoutPTY(cmd)
 }
 
+// FindExecSSHPath returns the ssh(1) executable RunExec uses, honoring
+// PANGOLIN_SSH_BINARY.
+func FindExecSSHPath() (string, error) {
+	return findExecSSHPath()
+}
+
+// WriteExecKeyFiles writes a JIT key and certificate to owner-only temp
+// files for an OpenSSH-family client. The caller must call cleanup.
+func WriteExecKeyFiles(privPEM, cert string) (keyPath, certPath string, cleanup func(), err error) {
+	return writeExecKeyFiles(RunOpts{PrivateKeyPEM: privPEM, Certificate: cert})
+}
+
 // writeExecKeyFiles writes PrivateKeyPEM and Certificate to temp files for system ssh.
 // Returns keyPath, certPath, cleanup func, error.
 func writeExecKeyFiles(opts RunOpts) (keyPath, certPath string, cleanup func(), err error) {
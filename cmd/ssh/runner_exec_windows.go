@@ -39,6 +39,18 @@ func findExecSSHPathWindows() (string, error) {
 	return "", errors.New("ssh executable not found in PATH or in OpenSSH location (C:\\Windows\\System32\\OpenSSH\\ssh.exe)")
 }
 
+// FindExecSSHPath returns the ssh(1) executable RunExec uses, honoring
+// PANGOLIN_SSH_BINARY.
+func FindExecSSHPath() (string, error) {
+	return findExecSSHPathWindows()
+}
+
+// WriteExecKeyFiles writes a JIT key and certificate to owner-only temp
+// files for an OpenSSH-family client. The caller must call cleanup.
+func WriteExecKeyFiles(privPEM, cert string) (keyPath, certPath string, cleanup func(), err error) {
+	return writeExecKeyFilesWindows(RunOpts{PrivateKeyPEM: privPEM, Certificate: cert})
+}
+
 func execExitCode(err error) int {
 	if err == nil {
 		return 0
@@ -66,7 +78,7 @@ func RunExec(opts RunOpts) (int, error) {
 		defer cleanup()
 	}
 
-	argv := buildExecSSHArgs(sshPath, opts.User, opts.Hostname, opts.Port, keyPath, certPath, opts.SSHPassthrough)
+	argv := buildExecSSHArgs(sshPath, opts.User, opts.Hostname, opts.Port, keyPath, certPath, opts.KnownHostsFile, opts.StrictHostKeys, opts.SSHPassthrough)
 	cmd := exec.Command(argv[0], argv[1:]...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/sshknownhosts"
 	"github.com/mattn/go-isatty"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/term"
@@ -15,75 +18,144 @@ import (
 
 const nativeDefaultSSHPort = "22"
 
-// RunNative runs an interactive SSH session using the pure-Go client (golang.org/x/crypto/ssh).
+// RunNative runs an SSH session using the pure-Go client (golang.org/x/crypto/ssh).
 // It does not use the system ssh binary. opts.PrivateKeyPEM and opts.Certificate must be set (JIT key + signed cert).
+//
+// The pass-through options -L, -R, -D, -N, -t, and -T and a remote command are
+// supported; other options are ignored with a warning. The returned exit code
+// is the remote command's exit status.
 func RunNative(opts RunOpts) (int, error) {
-	addr, err := nativeSSHAddress(opts.Hostname, opts.Port)
+	nativeOpts, err := parseNativeOptions(opts.SSHPassthrough)
 	if err != nil {
-		return 1, err
+		return 255, err
+	}
+	if len(nativeOpts.Unsupported) > 0 {
+		logger.Warning("The following options are not supported by the built-in client and were ignored: %s", NativeStrippedWarning(nativeOpts.Unsupported))
+	}
+
+	addr, err := NativeSSHAddress(opts.Hostname, opts.Port)
+	if err != nil {
+		return 255, err
 	}
 
-	config, err := nativeSSHClientConfig(opts)
+	config, err := NativeSSHClientConfig(opts)
 	if err != nil {
-		return 1, err
+		return 255, err
 	}
 
 	client, err := ssh.Dial("tcp", addr, config)
 	if err != nil {
-		return 1, fmt.Errorf("ssh dial: %w", err)
+		return 255, fmt.Errorf("ssh dial: %w", err)
 	}
 	defer client.Close()
 
+	stopForwards, err := startForwards(client, nativeOpts)
+	if err != nil {
+		return 255, err
+	}
+	defer stopForwards()
+
+	if nativeOpts.NoCommand {
+		// Keep forwards open until interrupted or the server disconnects.
+		interrupted := make(chan os.Signal, 1)
+		signal.Notify(interrupted, os.Interrupt)
+		defer signal.Stop(interrupted)
+		closed := make(chan struct{})
+		go func() {
+			_ = client.Wait()
+			close(closed)
+		}()
+		select {
+		case <-interrupted:
+		case <-closed:
+		}
+		return 0, nil
+	}
+
 	session, err := client.NewSession()
 	if err != nil {
-		return 1, fmt.Errorf("ssh session: %w", err)
+		return 255, fmt.Errorf("ssh session: %w", err)
 	}
 	defer session.Close()
 
 	stdinFd := int(os.Stdin.Fd())
-	useRaw := isatty.IsTerminal(uintptr(stdinFd))
-	if useRaw {
-		oldState, err := term.MakeRaw(stdinFd)
-		if err != nil {
-			return 1, err
-		}
-		defer func() { _ = term.Restore(stdinFd, oldState) }()
+	stdinIsTerminal := isatty.IsTerminal(uintptr(stdinFd))
+
+	// Same rules as ssh(1): a PTY for interactive shells on a terminal, none
+	// for remote commands unless -t is given; -T always disables it.
+	wantPTY := stdinIsTerminal && len(nativeOpts.Command) == 0
+	if nativeOpts.ForceTTY {
+		wantPTY = true
+	}
+	if nativeOpts.DisableTTY {
+		wantPTY = false
 	}
 
-	width, height := 80, 24
-	if useRaw {
-		if w, h, err := term.GetSize(stdinFd); err == nil {
-			width, height = w, h
+	if wantPTY {
+		if stdinIsTerminal {
+			oldState, err := term.MakeRaw(stdinFd)
+			if err != nil {
+				return 255, err
+			}
+			defer func() { _ = term.Restore(stdinFd, oldState) }()
 		}
-	}
 
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          1,
-		ssh.TTY_OP_ISPEED: 14400,
-		ssh.TTY_OP_OSPEED: 14400,
-	}
-	if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
-		return 1, fmt.Errorf("request pty: %w", err)
-	}
+		width, height := 80, 24
+		if stdinIsTerminal {
+			if w, h, err := term.GetSize(stdinFd); err == nil {
+				width, height = w, h
+			}
+		}
+
+		modes := ssh.TerminalModes{
+			ssh.ECHO:          1,
+			ssh.TTY_OP_ISPEED: 14400,
+			ssh.TTY_OP_OSPEED: 14400,
+		}
+		if err := session.RequestPty("xterm-256color", height, width, modes); err != nil {
+			return 255, fmt.Errorf("request pty: %w", err)
+		}
 
-	// Setup terminal window resize handling (platform-specific)
-	if useRaw {
-		setupWindowChangeHandler(session, stdinFd)
+		// Setup terminal window resize handling (platform-specific)
+		if stdinIsTerminal {
+			setupWindowChangeHandler(session, stdinFd)
+		}
 	}
 
 	session.Stdin = os.Stdin
 	session.Stdout = os.Stdout
 	session.Stderr = os.Stderr
 
-	if err := session.Shell(); err != nil {
-		return 1, fmt.Errorf("shell: %w", err)
+	if len(nativeOpts.Command) > 0 {
+		err = session.Start(strings.Join(nativeOpts.Command, " "))
+		if err != nil {
+			return 255, fmt.Errorf("exec: %w", err)
+		}
+	} else if err := session.Shell(); err != nil {
+		return 255, fmt.Errorf("shell: %w", err)
 	}
 
-	if err := session.Wait(); err != nil {
-		// Session ended with an error (e.g. exit 1 on remote). No numeric code in protocol.
-		return 1, nil
+	return nativeExitCode(session.Wait())
+}
+
+// nativeExitCode maps the result of session.Wait to an exit code the way
+// ssh(1) does: the remote exit status, or 255 when there is none.
+func nativeExitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.Signal() != "" {
+			return 255, nil
+		}
+		return exitErr.ExitStatus(), nil
 	}
-	return 0, nil
+	var missing *ssh.ExitMissingError
+	if errors.As(err, &missing) {
+		return 255, nil
+	}
+	return 255, err
 }
 
 func looksLikeCertificate(data []byte) bool {
@@ -92,7 +164,9 @@ func looksLikeCertificate(data []byte) bool {
 		strings.Contains(s, "ssh-rsa-cert") || strings.Contains(s, "ssh-ed25519-cert") || strings.Contains(s, "ecdsa-sha2-nistp256-cert")
 }
 
-func nativeSSHAddress(hostname string, port int) (string, error) {
+// NativeSSHAddress returns the host:port to dial for hostname, applying port
+// when it is set and defaulting to 22.
+func NativeSSHAddress(hostname string, port int) (string, error) {
 	if hostname == "" {
 		return "", errors.New("hostname is empty")
 	}
@@ -109,7 +183,10 @@ func nativeSSHAddress(hostname string, port int) (string, error) {
 	return net.JoinHostPort(hostname, nativeDefaultSSHPort), nil
 }
 
-func nativeSSHClientConfig(opts RunOpts) (*ssh.ClientConfig, error) {
+// NativeSSHClientConfig returns the client configuration for authenticating
+// with opts' JIT key and certificate and verifying the host against
+// opts.KnownHostsFile.
+func NativeSSHClientConfig(opts RunOpts) (*ssh.ClientConfig, error) {
 	if opts.PrivateKeyPEM == "" {
 		return nil, errors.New("private key required (JIT flow)")
 	}
@@ -151,10 +228,17 @@ func nativeSSHClientConfig(opts RunOpts) (*ssh.ClientConfig, error) {
 		}
 	}
 
+	// Without a known_hosts file the server offers no host certificate and
+	// only an ephemeral host key (see KnownHostsFile), so there is nothing
+	// to verify against.
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if opts.KnownHostsFile != "" {
+		hostKeyCallback = sshknownhosts.Callback(opts.KnownHostsFile)
+	}
+
 	return &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{ssh.PublicKeys(authSigner)},
-		// Host key verification disabled for simplicity; can be enhanced with known_hosts later.
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(authSigner)},
+		HostKeyCallback: hostKeyCallback,
 	}, nil
 }
@@ -6,5 +6,13 @@ type RunOpts struct {
 	Port          int
 	PrivateKeyPEM string
 	Certificate   string
+	// KnownHostsFile pins host keys in the given known_hosts file (trust on
+	// first use). Empty disables host key checking, which is only done for
+	// native SSH servers that offer no host certificate.
+	KnownHostsFile string
+	// StrictHostKeys refuses host keys KnownHostsFile does not already
+	// vouch for instead of pinning them, as for native SSH servers whose
+	// host CA KnownHostsFile has verified.
+	StrictHostKeys bool
 	SSHPassthrough
 }
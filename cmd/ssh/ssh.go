@@ -38,6 +38,8 @@ func SSHCmd() *cobra.Command {
 
 By default the system OpenSSH client is used on every platform. You can pass the same options as ssh(1) after the resource name (for example port forwards: -L, -R, -D, and -N), then an optional remote command. Example: pangolin ssh <resource> -L 8080:127.0.0.1:80 -N
 
+With --builtin the pure-Go client is used instead, for systems without OpenSSH. It supports remote commands, exit statuses, and the -L, -R, -D (SOCKS5), -N, -t, and -T options; other ssh(1) options are ignored.
+
 Set PANGOLIN_SSH_BINARY to the full path of ssh(1) to override PATH lookup on all platforms.`,
 		PreRunE: func(c *cobra.Command, args []string) error {
 			if len(args) < 1 || args[0] == "" {
@@ -58,28 +60,29 @@ Set PANGOLIN_SSH_BINARY to the full path of ssh(1) to override PATH lookup on al
 			return nil
 		},
 		Run: func(c *cobra.Command, args []string) {
-			client := olm.NewClient("")
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				logger.Error("%v", err)
+				os.Exit(1)
+			}
+
+			// Use the client instance connected to the resource's organization.
+			client := olm.ClientForOrg(orgID)
 			if !client.IsRunning() {
 				logger.Error("%v", errNoClientRunning)
 				os.Exit(1)
 			}
 
-			apiClient := api.FromContext(c.Context())
-			accountStore := config.AccountStoreFromContext(c.Context())
-
 			// init a jit connection to the site if we need to because we might not be connected
-			_, err := client.JITConnectByResourceID(opts.ResourceID)
+			_, err = client.JITConnectByResourceID(opts.ResourceID)
 			if err != nil {
 				logger.Warning("%v", err) // we pass through this warning for backward compatibility with older olm api servers
 			}
 
-			orgID, err := utils.ResolveOrgID(accountStore, "")
-			if err != nil {
-				logger.Error("%v", err)
-				os.Exit(1)
-			}
-
-			privPEM, _, cert, signData, err := GenerateAndSignKey(apiClient, orgID, opts.ResourceID, opts.Username)
+			privPEM, _, cert, signData, err := SignKeyCached(apiClient, accountStore, orgID, opts.ResourceID, opts.Username)
 			if err != nil {
 				logger.Error("%v", err)
 				os.Exit(1)
@@ -89,16 +92,7 @@ Set PANGOLIN_SSH_BINARY to the full path of ssh(1) to override PATH lookup on al
 				os.Exit(1)
 			}
 
-			siteIDs := []int{}
-			if signData.SiteID != 0 {
-				siteIDs = append(siteIDs, signData.SiteID)
-			}
-			for _, id := range signData.SiteIDs {
-				if id != 0 {
-					siteIDs = append(siteIDs, id)
-				}
-			}
-
+			siteIDs := signDataSiteIDs(signData)
 			if len(siteIDs) > 0 { // older versions of the server did not send back the site id so we need to check for backward compatibility
 				if err := waitForAnySiteConnection(client, siteIDs); err != nil {
 					logger.Error("%v", err)
@@ -120,21 +114,25 @@ Set PANGOLIN_SSH_BINARY to the full path of ssh(1) to override PATH lookup on al
 				}
 			}
 
+			knownHostsFile, err := KnownHostsFile(orgID, signData, opts.Port)
+			if err != nil {
+				logger.Error("%v", err)
+				os.Exit(1)
+			}
+
 			runOpts := RunOpts{
 				User:           signData.User,
 				Hostname:       signData.Hostname,
 				Port:           opts.Port,
 				PrivateKeyPEM:  privPEM,
 				Certificate:    cert,
+				KnownHostsFile: knownHostsFile,
+				StrictHostKeys: signData.AuthDaemonMode == "native",
 				SSHPassthrough: pt,
 			}
 
-			useBuiltin := opts.Builtin
-			if len(passThrough) > 0 && useBuiltin {
-				logger.Warning("Extra arguments after the resource are ignored by the built-in client (port forwarding, remote commands, and other ssh(1) options). Omit --builtin to use the system OpenSSH client.")
-			}
 			var exitCode int
-			if useBuiltin {
+			if opts.Builtin {
 				exitCode, err = RunNative(runOpts)
 			} else {
 				exitCode, err = RunExec(runOpts)
@@ -147,10 +145,18 @@ Set PANGOLIN_SSH_BINARY to the full path of ssh(1) to override PATH lookup on al
 		},
 	}
 
-	cmd.Flags().BoolVar(&opts.Builtin, "builtin", false, "Use the built-in SSH client instead of the system OpenSSH binary (interactive shell only)")
+	cmd.Flags().BoolVar(&opts.Builtin, "builtin", false, "Use the built-in SSH client instead of the system OpenSSH binary (supports remote commands and -L, -R, -D, -N, -t, -T)")
 	cmd.Flags().IntVarP(&opts.Port, "port", "p", 0, "Remote SSH port (default: 22)")
 
 	cmd.AddCommand(SignCmd())
+	cmd.AddCommand(CertsCmd())
+	cmd.AddCommand(SSHConfigCmd())
+	cmd.AddCommand(ProxyCmd())
+	cmd.AddCommand(KnownHostsCmd())
+	cmd.AddCommand(ExecCmd())
+	if agentCmd := AgentCmd(); agentCmd != nil {
+		cmd.AddCommand(agentCmd)
+	}
 
 	return cmd
 }
@@ -0,0 +1,429 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fosrl/cli/cmd/list"
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sshConfigFileName    = "pangolin.conf"
+	sshConfigStateDir    = "ssh"
+	defaultWatchInterval = 5 * time.Minute
+)
+
+var errInvalidAlias = errors.New("invalid resource alias")
+
+// SSHConfigCmd is the parent `ssh config` command for OpenSSH integration.
+func SSHConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Integrate Pangolin resources with the system OpenSSH client",
+	}
+	cmd.AddCommand(sshConfigGenerateCmd())
+	cmd.AddCommand(sshConfigPrepareCmd())
+	return cmd
+}
+
+func sshConfigGenerateCmd() *cobra.Command {
+	opts := struct {
+		Output   string
+		Labels   []string
+		Watch    bool
+		Interval time.Duration
+	}{}
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Write an OpenSSH config include for every reachable alias",
+		Long: `Writes an OpenSSH config file (default: ~/.ssh/pangolin.conf) with one entry per private host alias you can reach in the selected organization.
+
+Each entry calls back into pangolin when you connect to mint a certificate and resolve the SSH host and user, so plain ssh, scp, rsync, git, and other OpenSSH-based tools work without wrapping them in pangolin ssh:
+
+  pangolin ssh config generate
+  ssh my-server.internal
+
+Add this line near the top of ~/.ssh/config (before any Host or Match blocks) to enable it:
+
+  Include ~/.ssh/pangolin.conf
+
+With --watch the file is regenerated whenever the set of aliases changes.`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				return err
+			}
+
+			output := opts.Output
+			if output == "" {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return err
+				}
+				output = filepath.Join(home, ".ssh", sshConfigFileName)
+			}
+
+			changed, count, err := generateSSHConfig(apiClient, orgID, opts.Labels, output)
+			if err != nil {
+				return err
+			}
+			if changed {
+				logger.Success("Wrote %d host entries to %s", count, output)
+			} else {
+				logger.Info("%s is up to date (%d host entries)", output, count)
+			}
+			printSSHConfigIncludeHint(output)
+
+			if !opts.Watch {
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logger.Info("Watching for alias changes every %s", opts.Interval)
+			ticker := time.NewTicker(opts.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+				}
+
+				changed, count, err := generateSSHConfig(apiClient, orgID, opts.Labels, output)
+				if err != nil {
+					logger.Warning("Failed to regenerate %s: %v", output, err)
+					continue
+				}
+				if changed {
+					logger.Success("Aliases changed; wrote %d host entries to %s", count, output)
+				}
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Path of the generated file (default: ~/.ssh/pangolin.conf)")
+	cmd.Flags().StringSliceVarP(&opts.Labels, "label", "l", nil, "Only include aliases for resources with this label (repeatable, OR)")
+	cmd.Flags().BoolVarP(&opts.Watch, "watch", "w", false, "Keep running and regenerate the file when aliases change")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", defaultWatchInterval, "How often to check for alias changes with --watch")
+
+	return cmd
+}
+
+// sshConfigPrepareCmd is invoked by OpenSSH from the `Match exec` lines in
+// the generated file. It mints (or reuses) a certificate for the alias and
+// writes the per-host settings that the matching block includes.
+func sshConfigPrepareCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "prepare <alias>",
+		Short:  "Prepare a certificate and host settings for OpenSSH (used by the generated config)",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			alias := args[0]
+			if err := validateAlias(alias); err != nil {
+				return err
+			}
+
+			apiClient := api.FromContext(c.Context())
+			accountStore := config.AccountStoreFromContext(c.Context())
+
+			orgID, err := utils.ResolveOrgID(accountStore, "")
+			if err != nil {
+				return err
+			}
+
+			client := olm.ClientForOrg(orgID)
+			if !client.IsRunning() {
+				return errNoClientRunning
+			}
+
+			if _, err := client.JITConnectByResourceID(alias); err != nil {
+				logger.Debug("JIT connect for %s: %v", alias, err)
+			}
+
+			privPEM, _, cert, signData, err := SignKeyCached(apiClient, accountStore, orgID, alias, "")
+			if err != nil {
+				return err
+			}
+			if signData == nil || signData.Hostname == "" {
+				return errHostnameRequired
+			}
+
+			if siteIDs := signDataSiteIDs(signData); len(siteIDs) > 0 {
+				if err := waitForSiteConnectionQuiet(client, siteIDs); err != nil {
+					return err
+				}
+			}
+
+			knownHostsFile, err := KnownHostsFile(orgID, signData, 0)
+			if err != nil {
+				return err
+			}
+
+			return writeSSHHostFiles(alias, privPEM, cert, knownHostsFile, signData)
+		},
+	}
+}
+
+// generateSSHConfig fetches aliases and rewrites output when its content
+// changed. It reports whether the file was written and how many aliases it
+// contains.
+func generateSSHConfig(apiClient *api.Client, orgID string, labels []string, output string) (bool, int, error) {
+	aliases, err := list.FetchAliases(apiClient, orgID, labels)
+	if err != nil {
+		return false, 0, err
+	}
+
+	valid := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		if err := validateAlias(alias); err != nil {
+			logger.Debug("Skipping alias %q: %v", alias, err)
+			continue
+		}
+		valid = append(valid, alias)
+	}
+	sort.Strings(valid)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return false, 0, fmt.Errorf("locate pangolin executable: %w", err)
+	}
+	hostsDir, err := sshStateDir("hosts")
+	if err != nil {
+		return false, 0, err
+	}
+
+	content := renderSSHConfig(exe, hostsDir, valid)
+
+	existing, err := os.ReadFile(output)
+	if err == nil && bytes.Equal(existing, content) {
+		return false, len(valid), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0o700); err != nil {
+		return false, 0, fmt.Errorf("create %s: %w", filepath.Dir(output), err)
+	}
+	if err := writeFileAtomic(output, content, 0o600); err != nil {
+		return false, 0, err
+	}
+
+	removeStaleHostFiles(valid)
+	return true, len(valid), nil
+}
+
+func renderSSHConfig(exe, hostsDir string, aliases []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("# Generated by `pangolin ssh config generate`; changes will be overwritten.\n")
+	b.WriteString("# Include it near the top of ~/.ssh/config:\n")
+	b.WriteString("#   Include ~/.ssh/" + sshConfigFileName + "\n")
+
+	prepare := sshConfigQuote(exe) + " ssh config prepare %n"
+	for _, alias := range aliases {
+		fmt.Fprintf(&b, "\nMatch host %s exec \"%s\"\n", alias, prepare)
+		fmt.Fprintf(&b, "    Include \"%s\"\n", filepath.ToSlash(filepath.Join(hostsDir, alias+".conf")))
+	}
+	return b.Bytes()
+}
+
+// sshConfigQuote quotes the executable path for the shell that OpenSSH runs
+// `Match exec` commands with.
+func sshConfigQuote(path string) string {
+	if !strings.ContainsAny(path, " '\"\\$`") {
+		return path
+	}
+	return "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
+
+func printSSHConfigIncludeHint(output string) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	userConfig, err := os.ReadFile(filepath.Join(home, ".ssh", "config"))
+	if err == nil && bytes.Contains(userConfig, []byte(filepath.Base(output))) {
+		return
+	}
+
+	include := output
+	if rel, err := filepath.Rel(home, output); err == nil && !strings.HasPrefix(rel, "..") {
+		include = "~/" + filepath.ToSlash(rel)
+	}
+	fmt.Println()
+	logger.Info("Add this line near the top of ~/.ssh/config to enable it:")
+	logger.Info("  Include %s", include)
+}
+
+// writeSSHHostFiles writes the key, certificate, and host settings that the
+// generated `Match` block for alias includes.
+func writeSSHHostFiles(alias, privPEM, cert, knownHostsFile string, signData *api.SignSSHKeyData) error {
+	hostsDir, err := sshStateDir("hosts")
+	if err != nil {
+		return err
+	}
+	keysDir, err := sshStateDir("keys")
+	if err != nil {
+		return err
+	}
+
+	keyPath := filepath.Join(keysDir, alias)
+	certPath := keyPath + "-cert.pub"
+	if err := writeFileAtomic(keyPath, []byte(privPEM), 0o600); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(certPath, []byte(cert), 0o600); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "HostName %s\n", signData.Hostname)
+	if signData.User != "" {
+		fmt.Fprintf(&b, "User %s\n", signData.User)
+	}
+	fmt.Fprintf(&b, "IdentityFile \"%s\"\n", filepath.ToSlash(keyPath))
+	fmt.Fprintf(&b, "CertificateFile \"%s\"\n", filepath.ToSlash(certPath))
+	b.WriteString("IdentitiesOnly yes\n")
+	if knownHostsFile != "" {
+		fmt.Fprintf(&b, "UserKnownHostsFile \"%s\"\n", filepath.ToSlash(knownHostsFile))
+		if signData.AuthDaemonMode == "native" {
+			b.WriteString("StrictHostKeyChecking yes\n")
+		} else {
+			b.WriteString("StrictHostKeyChecking accept-new\n")
+		}
+	} else {
+		b.WriteString("UserKnownHostsFile /dev/null\n")
+		b.WriteString("StrictHostKeyChecking no\n")
+	}
+
+	return writeFileAtomic(filepath.Join(hostsDir, alias+".conf"), b.Bytes(), 0o600)
+}
+
+func removeStaleHostFiles(aliases []string) {
+	keep := make(map[string]bool, len(aliases))
+	for _, alias := range aliases {
+		keep[alias] = true
+	}
+
+	hostsDir, err := sshStateDir("hosts")
+	if err != nil {
+		return
+	}
+	keysDir, err := sshStateDir("keys")
+	if err != nil {
+		return
+	}
+	entries, err := os.ReadDir(hostsDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		alias := strings.TrimSuffix(e.Name(), ".conf")
+		if e.IsDir() || alias == e.Name() || keep[alias] {
+			continue
+		}
+		_ = os.Remove(filepath.Join(hostsDir, e.Name()))
+		_ = os.Remove(filepath.Join(keysDir, alias))
+		_ = os.Remove(filepath.Join(keysDir, alias+"-cert.pub"))
+	}
+}
+
+// sshStateDir returns (and creates) a subdirectory of the CLI's ssh state.
+func sshStateDir(name string) (string, error) {
+	dir, err := config.GetPangolinConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, sshConfigStateDir, name)
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return "", fmt.Errorf("create %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// validateAlias rejects aliases that are not safe to use as a file name or
+// inside an OpenSSH config line.
+func validateAlias(alias string) error {
+	if alias == "" || alias == "." || alias == ".." {
+		return errInvalidAlias
+	}
+	for _, r := range alias {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '.', r == '-', r == '_':
+		default:
+			return fmt.Errorf("%w %q", errInvalidAlias, alias)
+		}
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// signDataSiteIDs returns the non-zero site IDs the signing response refers to.
+func signDataSiteIDs(signData *api.SignSSHKeyData) []int {
+	siteIDs := []int{}
+	if signData.SiteID != 0 {
+		siteIDs = append(siteIDs, signData.SiteID)
+	}
+	for _, id := range signData.SiteIDs {
+		if id != 0 {
+			siteIDs = append(siteIDs, id)
+		}
+	}
+	return siteIDs
+}
+
+// waitForSiteConnectionQuiet is waitForAnySiteConnection without the
+// spinner, for callers that are not attached to a terminal (e.g. OpenSSH
+// running `Match exec` or a ProxyCommand).
+func waitForSiteConnectionQuiet(client *olm.Client, siteIDs []int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), siteAppearTimeout+siteConnectTimeout)
+	defer cancel()
+
+	for {
+		status, err := client.GetStatus()
+		if err == nil {
+			for _, siteID := range siteIDs {
+				if peer, ok := status.PeerStatuses[siteID]; ok && peer.Connected {
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return errors.New("timed out waiting for site to connect")
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package ssh
+
+import (
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/utils"
+)
+
+// Target is a resource's SSH host together with a just-in-time key signed
+// for it, ready for an OpenSSH-family client to connect.
+type Target struct {
+	OrgID          string
+	ResourceID     string
+	PrivateKeyPEM  string
+	Certificate    string
+	SignData       *api.SignSSHKeyData
+	KnownHostsFile string
+}
+
+// Native reports whether the host runs the native SSH server, which supports
+// only a subset of client options.
+func (t *Target) Native() bool {
+	return t.SignData.AuthDaemonMode == "native"
+}
+
+// PrepareTarget does what pangolin ssh does before starting a client: it
+// connects the running client to resourceID's site, signs a key for username
+// (the resource's default user when empty), and waits for the site to come
+// up.
+func PrepareTarget(apiClient *api.Client, accountStore *config.AccountStore, resourceID, username string) (*Target, error) {
+	return prepareTarget(apiClient, accountStore, resourceID, username, waitForAnySiteConnection)
+}
+
+// prepareTarget is PrepareTarget with the site wait supplied by the caller,
+// so commands that prepare several targets at once can wait without a
+// spinner.
+func prepareTarget(apiClient *api.Client, accountStore *config.AccountStore, resourceID, username string, waitForSite func(*olm.Client, []int) error) (*Target, error) {
+	orgID, err := utils.ResolveOrgID(accountStore, "")
+	if err != nil {
+		return nil, err
+	}
+
+	client := olm.ClientForOrg(orgID)
+	if !client.IsRunning() {
+		return nil, errNoClientRunning
+	}
+
+	// Older olm API servers do not support JIT connect; keep going.
+	if _, err := client.JITConnectByResourceID(resourceID); err != nil {
+		logger.Warning("%v", err)
+	}
+
+	privPEM, _, cert, signData, err := SignKeyCached(apiClient, accountStore, orgID, resourceID, username)
+	if err != nil {
+		return nil, err
+	}
+	if signData == nil || signData.Hostname == "" {
+		return nil, errHostnameRequired
+	}
+
+	if siteIDs := signDataSiteIDs(signData); len(siteIDs) > 0 {
+		if err := waitForSite(client, siteIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	knownHostsFile, err := KnownHostsFile(orgID, signData, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Target{
+		OrgID:          orgID,
+		ResourceID:     resourceID,
+		PrivateKeyPEM:  privPEM,
+		Certificate:    cert,
+		SignData:       signData,
+		KnownHostsFile: knownHostsFile,
+	}, nil
+}
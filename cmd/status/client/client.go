@@ -2,10 +2,13 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"time"
 
+	"github.com/fosrl/cli/internal/config"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/fosrl/cli/internal/olm"
 	"github.com/fosrl/cli/internal/utils"
@@ -13,7 +16,10 @@ import (
 )
 
 type ClientStatusCmdOpts = struct {
-	JSON bool
+	JSON     bool
+	Instance string
+	All      bool
+	Watch    bool
 }
 
 func ClientStatusCmd() *cobra.Command {
@@ -22,22 +28,60 @@ func ClientStatusCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "client",
 		Short: "Show client status",
-		Long:  "Display current client connection status and peer information",
+		Long: `Display current client connection status and peer information. When several client instances are running, each is shown unless --instance picks one.
+
+With --json the status of one instance is printed as an object; with several running, pick one with --instance. With --all every running instance is shown, and --json prints an object keyed by instance name.
+
+With --watch the status is shown full screen and refreshed every second. Peers whose state changed are highlighted; select a peer with the arrow keys and press c to connect its site, or press l to show the client log.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := clientStatusMain(&opts); err != nil {
+			if err := clientStatusMain(cmd, &opts); err != nil {
 				os.Exit(1)
 			}
 		},
 	}
 
 	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print raw JSON response")
+	cmd.Flags().StringVar(&opts.Instance, "instance", "", "Show only the named client `instance`")
+	cmd.Flags().BoolVar(&opts.All, "all", false, "Show every running client instance")
+	cmd.Flags().BoolVarP(&opts.Watch, "watch", "w", false, "Show a live, full-screen view of the status")
+	cmd.MarkFlagsMutuallyExclusive("json", "watch")
+	cmd.MarkFlagsMutuallyExclusive("all", "instance", "watch")
 
 	return cmd
 }
 
-func clientStatusMain(opts *ClientStatusCmdOpts) error {
-	// Get socket path from config or use default
-	client := olm.NewClient("")
+func clientStatusMain(cmd *cobra.Command, opts *ClientStatusCmdOpts) error {
+	cfg := config.ConfigFromContext(cmd.Context())
+
+	if opts.All {
+		return printInstances(cfg, opts, olm.RunningInstances())
+	}
+	if opts.Instance == "" && !opts.Watch {
+		if instances := olm.RunningInstances(); len(instances) > 1 {
+			// The JSON output is a single instance's status unless --all
+			// is given, so scripts always get the same shape.
+			if opts.JSON {
+				err := errors.New("several client instances are running; pick one with --instance or use --all")
+				logger.Error("Error: %v", err)
+				return err
+			}
+			return printInstances(cfg, opts, instances)
+		}
+	}
+
+	// With a single client running, show it whatever its instance.
+	inst := olm.NewInstance(olm.InstanceName(opts.Instance))
+	if opts.Instance == "" {
+		if instances := olm.RunningInstances(); len(instances) > 0 {
+			if opts.Watch && len(instances) > 1 {
+				err := errors.New("several client instances are running; pick one with --instance")
+				logger.Error("Error: %v", err)
+				return err
+			}
+			inst = instances[0]
+		}
+	}
+	client := inst.Client()
 
 	// Check if client is running
 	if !client.IsRunning() {
@@ -51,6 +95,15 @@ func clientStatusMain(opts *ClientStatusCmdOpts) error {
 		logger.Error("Error: %v", err)
 		return err
 	}
+	status.Reconnect = olm.ReadReconnectState(olm.ReconnectStatePath(cfg.EventJournalDir(), inst.Name))
+
+	if opts.Watch {
+		if err := runWatch(client, cfg.ClientLogFile(inst.Name)); err != nil {
+			logger.Error("Error: %v", err)
+			return err
+		}
+		return nil
+	}
 
 	// Print raw JSON if flag is set, otherwise print formatted table
 	if opts.JSON {
@@ -62,6 +115,44 @@ func clientStatusMain(opts *ClientStatusCmdOpts) error {
 	return nil
 }
 
+// printInstances prints the status of the running instances, as one JSON
+// object keyed by instance name or as a table per instance.
+func printInstances(cfg *config.Config, opts *ClientStatusCmdOpts, instances []olm.Instance) error {
+	statuses := make(map[string]*olm.StatusResponse, len(instances))
+	for _, inst := range instances {
+		status, err := inst.Client().GetStatus()
+		if err != nil {
+			logger.Error("Error: instance %s: %v", inst.DisplayName(), err)
+			return err
+		}
+		status.Reconnect = olm.ReadReconnectState(olm.ReconnectStatePath(cfg.EventJournalDir(), inst.Name))
+		statuses[inst.DisplayName()] = status
+	}
+
+	if opts.JSON {
+		jsonData, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			logger.Error("Error marshaling JSON: %v", err)
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	if len(instances) == 0 {
+		logger.Info("No client is currently running")
+		return nil
+	}
+	for i, inst := range instances {
+		if i > 0 {
+			fmt.Println("")
+		}
+		fmt.Printf("Instance: %s\n\n", inst.DisplayName())
+		printStatusTable(statuses[inst.DisplayName()])
+	}
+	return nil
+}
+
 // printJSON prints the status response as JSON
 func printJSON(status *olm.StatusResponse) error {
 	jsonData, err := json.MarshalIndent(status, "", "  ")
@@ -87,13 +178,17 @@ func printStatusTable(status *olm.StatusResponse) {
 	}
 	utils.PrintTable(headers, rows)
 
+	if r := status.Reconnect; r != nil {
+		fmt.Printf("\nReconnecting: %s\n", formatReconnect(r))
+	}
+
 	// Print peers if there are any
 	if len(status.PeerStatuses) > 0 {
 		fmt.Println("")
-		peerHeaders := []string{"SITE", "ENDPOINT", "STATUS", "LAST SEEN", "CONNECTION"}
+		peerHeaders := []string{"SITE", "ENDPOINT", "STATUS", "LAST SEEN", "CONNECTION", "RTT"}
 		peerRows := [][]string{}
 
-		for _, peer := range status.PeerStatuses {
+		for _, peer := range sortedPeers(status) {
 			lastSeen := formatLastSeen(peer.LastSeen.Format(time.RFC3339))
 
 			peerRows = append(peerRows, []string{
@@ -102,6 +197,7 @@ func printStatusTable(status *olm.StatusResponse) {
 				formatStatus(peer.Connected, true), // Peers don't have registered field, use true
 				lastSeen,
 				formatConnectionMode(peer.IsLocal, peer.IsRelay),
+				formatRTT(peer.RTT),
 			})
 
 		}
@@ -111,6 +207,38 @@ func printStatusTable(status *olm.StatusResponse) {
 	}
 }
 
+// formatReconnect summarizes the supervisor's progress restarting the
+// tunnel.
+func formatReconnect(r *olm.ReconnectState) string {
+	s := fmt.Sprintf("attempt %d, in progress", r.Attempt)
+	if wait := time.Until(r.NextAttempt); wait > 0 {
+		s = fmt.Sprintf("attempt %d in %s", r.Attempt, wait.Round(time.Second))
+	}
+	if r.Reason != "" {
+		s += " (" + r.Reason + ")"
+	}
+	if r.LastError != "" {
+		s += "; last error: " + r.LastError
+	}
+	return s
+}
+
+// sortedPeers returns the status's peers ordered by site name, so they keep
+// their place from one run to the next.
+func sortedPeers(status *olm.StatusResponse) []*olm.OLMPeerStatus {
+	peers := make([]*olm.OLMPeerStatus, 0, len(status.PeerStatuses))
+	for _, peer := range status.PeerStatuses {
+		peers = append(peers, peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].SiteName != peers[j].SiteName {
+			return peers[i].SiteName < peers[j].SiteName
+		}
+		return peers[i].SiteID < peers[j].SiteID
+	})
+	return peers
+}
+
 // formatConnectionMode summarizes how a peer is currently connected. Local and relay are
 // mutually exclusive; when neither applies the peer is connected directly to its public
 // endpoint.
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fosrl/cli/internal/logger"
+	"github.com/fosrl/cli/internal/olm"
+)
+
+const (
+	watchInterval = time.Second
+	// watchHighlight is how long a peer stays highlighted after its state
+	// changed.
+	watchHighlight = 5 * time.Second
+	watchEvents    = 5
+	watchLogLines  = 8
+)
+
+var (
+	watchHeaderStyle  = lipgloss.NewStyle().Bold(true)
+	watchDimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorLightGray))
+	watchUpStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorSuccess))
+	watchDownStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorError))
+	watchChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(logger.ColorWarning)).Bold(true)
+	watchCursorStyle  = lipgloss.NewStyle().Reverse(true)
+)
+
+// peerState is what a transition is detected on.
+type peerState struct {
+	connected bool
+	mode      string
+}
+
+func stateOf(p *olm.OLMPeerStatus) peerState {
+	return peerState{connected: p.Connected, mode: formatConnectionMode(p.IsLocal, p.IsRelay)}
+}
+
+func (s peerState) String() string {
+	if !s.connected {
+		return "Disconnected"
+	}
+	return s.mode
+}
+
+type watchStatusMsg struct {
+	status *olm.StatusResponse
+	err    error
+}
+
+type watchTickMsg struct{}
+
+type watchNoticeMsg string
+
+// watchModel is a full-screen view of one client's status, refreshed in
+// place.
+type watchModel struct {
+	client  *olm.Client
+	logFile string
+
+	status  *olm.StatusResponse
+	err     error
+	updated time.Time
+
+	states  map[int]peerState
+	changed map[int]time.Time
+	events  []string
+	notice  string
+
+	cursor  int
+	showLog bool
+	width   int
+}
+
+func newWatchModel(client *olm.Client, logFile string) *watchModel {
+	return &watchModel{
+		client:  client,
+		logFile: logFile,
+		states:  map[int]peerState{},
+		changed: map[int]time.Time{},
+	}
+}
+
+// runWatch shows the dashboard until the user quits.
+func runWatch(client *olm.Client, logFile string) error {
+	_, err := tea.NewProgram(newWatchModel(client, logFile), tea.WithAltScreen()).Run()
+	return err
+}
+
+func (m *watchModel) Init() tea.Cmd {
+	return m.poll()
+}
+
+func (m *watchModel) poll() tea.Cmd {
+	client := m.client
+	return func() tea.Msg {
+		status, err := client.GetStatus()
+		return watchStatusMsg{status: status, err: err}
+	}
+}
+
+func (m *watchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "down", "j":
+			if m.cursor < len(m.peers())-1 {
+				m.cursor++
+			}
+		case "l":
+			m.showLog = !m.showLog
+		case "r":
+			return m, m.poll()
+		case "c":
+			return m, m.connectSelected()
+		}
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+
+	case watchTickMsg:
+		return m, m.poll()
+
+	case watchNoticeMsg:
+		m.notice = string(msg)
+		return m, nil
+
+	case watchStatusMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.observe(msg.status)
+			m.status = msg.status
+			m.updated = time.Now()
+			if n := len(m.peers()); m.cursor >= n {
+				m.cursor = max(n-1, 0)
+			}
+		}
+		return m, tea.Tick(watchInterval, func(time.Time) tea.Msg { return watchTickMsg{} })
+	}
+	return m, nil
+}
+
+// observe records peers whose state differs from the previous poll.
+func (m *watchModel) observe(status *olm.StatusResponse) {
+	now := time.Now()
+	for _, p := range status.PeerStatuses {
+		next := stateOf(p)
+		prev, seen := m.states[p.SiteID]
+		m.states[p.SiteID] = next
+		if !seen || prev == next {
+			continue
+		}
+		m.changed[p.SiteID] = now
+		m.events = append(m.events, fmt.Sprintf("%s  %s: %s -> %s", now.Format("15:04:05"), p.SiteName, prev, next))
+	}
+	if len(m.events) > watchEvents {
+		m.events = m.events[len(m.events)-watchEvents:]
+	}
+}
+
+func (m *watchModel) peers() []*olm.OLMPeerStatus {
+	if m.status == nil {
+		return nil
+	}
+	return sortedPeers(m.status)
+}
+
+// connectSelected asks the client to connect the selected peer's site just
+// in time.
+func (m *watchModel) connectSelected() tea.Cmd {
+	peers := m.peers()
+	if m.cursor >= len(peers) {
+		return nil
+	}
+	peer := peers[m.cursor]
+	client := m.client
+	return func() tea.Msg {
+		if _, err := client.JITConnectBySiteID(strconv.Itoa(peer.SiteID)); err != nil {
+			return watchNoticeMsg(fmt.Sprintf("Connect %s: %v", peer.SiteName, err))
+		}
+		return watchNoticeMsg(fmt.Sprintf("Requested a connection to %s", peer.SiteName))
+	}
+}
+
+func (m *watchModel) View() string {
+	var sb strings.Builder
+
+	if m.status == nil {
+		if m.err != nil {
+			sb.WriteString(watchDownStyle.Render("Error: " + m.err.Error()))
+		} else {
+			sb.WriteString("Connecting to client...")
+		}
+		sb.WriteString("\n\n" + watchDimStyle.Render("q quit"))
+		return sb.String()
+	}
+
+	status := m.status
+	state := formatStatus(status.Connected, status.Registered)
+	stateStyle := watchUpStyle
+	if state != "Connected" {
+		stateStyle = watchDownStyle
+	}
+	sb.WriteString(watchHeaderStyle.Render("Pangolin client") + "  " + stateStyle.Render(state))
+	fmt.Fprintf(&sb, "  org %s  %s %s\n", status.OrgID, status.Agent, status.Version)
+	if m.err != nil {
+		sb.WriteString(watchDownStyle.Render("Last poll failed: "+m.err.Error()) + "\n")
+	} else {
+		sb.WriteString(watchDimStyle.Render("Updated "+m.updated.Format("15:04:05")) + "\n")
+	}
+	if status.Error != nil {
+		sb.WriteString(watchDownStyle.Render("Error: "+status.Error.Message) + "\n")
+	}
+	sb.WriteString("\n")
+
+	peers := m.peers()
+	if len(peers) == 0 {
+		sb.WriteString("No peers connected\n")
+	} else {
+		sb.WriteString(watchHeaderStyle.Render(fmt.Sprintf("%-24s %-13s %-9s %-19s %s", "SITE", "STATE", "RTT", "LAST SEEN", "ENDPOINT")) + "\n")
+		for i, p := range peers {
+			st := stateOf(p)
+			lastSeen := "-"
+			if !p.LastSeen.IsZero() {
+				lastSeen = formatLastSeen(p.LastSeen.Format(time.RFC3339))
+			}
+			line := fmt.Sprintf("%-24s %-13s %-9s %-19s %s",
+				truncate(p.SiteName, 24), st, formatRTT(p.RTT), lastSeen, p.Endpoint)
+
+			style := watchUpStyle
+			if !st.connected {
+				style = watchDownStyle
+			}
+			if t, ok := m.changed[p.SiteID]; ok && time.Since(t) < watchHighlight {
+				style = watchChangedStyle
+			}
+			if i == m.cursor {
+				style = style.Inherit(watchCursorStyle)
+			}
+			sb.WriteString(style.Render(line) + "\n")
+		}
+	}
+
+	if len(m.events) > 0 {
+		sb.WriteString("\n" + watchHeaderStyle.Render("Recent changes") + "\n")
+		for _, e := range m.events {
+			sb.WriteString(watchDimStyle.Render(e) + "\n")
+		}
+	}
+
+	if m.showLog {
+		sb.WriteString("\n" + watchHeaderStyle.Render("Log") + "\n")
+		lines := tailLines(m.logFile, watchLogLines)
+		if len(lines) == 0 {
+			sb.WriteString(watchDimStyle.Render("(no log output in "+m.logFile+")") + "\n")
+		}
+		for _, line := range lines {
+			if m.width > 0 {
+				line = truncate(line, m.width)
+			}
+			sb.WriteString(watchDimStyle.Render(line) + "\n")
+		}
+	}
+
+	sb.WriteString("\n")
+	if m.notice != "" {
+		sb.WriteString(m.notice + "\n")
+	}
+	sb.WriteString(watchDimStyle.Render("↑/↓ select  c connect site  l toggle log  r refresh  q quit"))
+	return sb.String()
+}
+
+func formatRTT(rtt time.Duration) string {
+	if rtt <= 0 {
+		return "-"
+	}
+	return rtt.Round(100 * time.Microsecond).String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 3 {
+		return s[:n]
+	}
+	return s[:n-3] + "..."
+}
+
+// tailLines returns up to n of the last lines of the file at path.
+func tailLines(path string, n int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	const window = 16 << 10
+	if info, err := f.Stat(); err == nil && info.Size() > window {
+		if _, err := f.Seek(info.Size()-window, io.SeekStart); err != nil {
+			return nil
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
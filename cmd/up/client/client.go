@@ -12,31 +12,48 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/companion"
 	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/events"
 	"github.com/fosrl/cli/internal/fingerprint"
+	"github.com/fosrl/cli/internal/killswitch"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/fosrl/cli/internal/olm"
+	"github.com/fosrl/cli/internal/service"
 	"github.com/fosrl/cli/internal/tui"
 	"github.com/fosrl/cli/internal/utils"
 	versionpkg "github.com/fosrl/cli/internal/version"
 	newtLogger "github.com/fosrl/newt/logger"
+	dnsOverride "github.com/fosrl/olm/dns/override"
 	olmpkg "github.com/fosrl/olm/olm"
 	"github.com/spf13/cobra"
 )
 
 const (
-	defaultEnableAPI  = true
-	defaultSocketPath = "/var/run/olm.sock"
-	defaultAgent      = olm.AgentName
+	defaultEnableAPI = true
+	defaultAgent     = olm.AgentName
+
+	// handoffTimeout bounds how long a detached start waits for the
+	// subprocess to read its credentials.
+	handoffTimeout = 10 * time.Second
+
+	// eventPollInterval is how often the client's status is compared with
+	// the last snapshot to journal connection events.
+	eventPollInterval = 2 * time.Second
 )
 
 type ClientUpCmdOpts struct {
 	ID                string
 	Secret            string
+	SecretFile        string
+	CredentialsFile   string
+	Profile           string
+	Instance          string
 	Endpoint          string
 	OrgID             string
 	MTU               int
@@ -55,6 +72,11 @@ type ClientUpCmdOpts struct {
 	UpstreamDNS       []string
 	MatchDomains      []string
 	PreferLocalRoutes bool
+	Reconnect         bool
+	KillSwitch        string
+
+	// userToken is the session token handed over by the parent process.
+	userToken string
 }
 
 // validateDNSIP ensures the given DNS server string is a valid IP address.
@@ -84,8 +106,60 @@ func ClientUpCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "client",
 		Short: "Start a client connection",
-		Long:  `Bring up a client tunneled connection.`,
+		Long: `Bring up a client tunneled connection.
+
+With --profile, flags that are not passed are taken from the named profile
+in the config file; see 'pangolin config profile'.
+
+In attached mode, credentials that are not passed as flags are read from the
+CLIENT_ID, CLIENT_SECRET (or CLIENT_SECRET_FILE), PANGOLIN_ENDPOINT and
+PANGOLIN_ORG environment variables. When the client service is installed
+('pangolin service install'), detached mode starts the service instead.
+
+The client restarts the tunnel with increasing delays when the server
+terminates it, and moves the tunnel to the new network when the host's
+addresses or default route change. When the server rejects the credentials
+of the logged-in account, they are replaced; when it rejects credentials
+given as flags or environment variables, the client exits with an error.
+'pangolin status' shows reconnect progress. Pass --reconnect=false to exit
+instead.
+
+On Linux, --kill-switch installs nftables (or iptables) rules that drop
+traffic to the organization's ranges, or with --kill-switch=all all traffic,
+unless it goes through the tunnel interface. The rules stay in place while
+the client reconnects and after it crashes, and are removed when it is
+stopped with 'pangolin down' or by 'pangolin reset-dns'. As --kill-switch=all
+would block the tunnels of other clients, it cannot be used alongside them.`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.ConfigFromContext(cmd.Context())
+
+			if opts.Profile != "" {
+				if err := applyUpProfile(cmd, cfg, opts.Profile); err != nil {
+					return err
+				}
+			}
+
+			if opts.CredentialsFile != "" {
+				h, err := readHandoff(opts.CredentialsFile)
+				if err != nil {
+					return err
+				}
+				opts.ID, opts.Secret, opts.userToken = h.ID, h.Secret, h.UserToken
+			} else if opts.Attached {
+				applyUpEnv(cmd, &opts)
+			}
+
+			if opts.Secret != "" && opts.SecretFile != "" {
+				return errors.New("--secret and --secret-file options conflict")
+			}
+			if opts.SecretFile != "" {
+				secret, err := readSecretFile(opts.SecretFile)
+				if err != nil {
+					return err
+				}
+				opts.Secret = secret
+			}
+
 			// `--id` and `--secret` must be specified together
 			if (opts.ID == "") != (opts.Secret == "") {
 				return errors.New("--id and --secret must be provided together")
@@ -95,7 +169,6 @@ func ClientUpCmd() *cobra.Command {
 				return errors.New("--silent and --attached options conflict")
 			}
 
-			cfg := config.ConfigFromContext(cmd.Context())
 			applyUpDefaults(cmd, &opts, cfg)
 
 			if err := validateDNSIP(opts.DNS, "netstack-dns"); err != nil {
@@ -118,7 +191,11 @@ func ClientUpCmd() *cobra.Command {
 
 	// Optional flags - if not provided, will use config or create new OLM
 	cmd.Flags().StringVar(&opts.ID, "id", "", "Client ID (optional, will use user info if not provided)")
-	cmd.Flags().StringVar(&opts.Secret, "secret", "", "Client secret (optional, will use user info if not provided)")
+	cmd.Flags().StringVar(&opts.Secret, "secret", "", "Client secret (optional, will use user info if not provided); visible to other local users, prefer --secret-file")
+	cmd.Flags().StringVar(&opts.SecretFile, "secret-file", "", "Read the client secret from `file`")
+	// Set by a detached parent to hand credentials to the elevated client.
+	cmd.Flags().StringVar(&opts.CredentialsFile, "credentials-file", "", "")
+	_ = cmd.Flags().MarkHidden("credentials-file")
 
 	// Optional flags
 	cmd.Flags().StringVar(&opts.OrgID, "org", "", "Organization ID (default: selected organization if logged in)")
@@ -137,13 +214,66 @@ func ClientUpCmd() *cobra.Command {
 	cmd.Flags().StringSliceVar(&opts.UpstreamDNS, "upstream-dns", []string{}, "List of DNS servers to use for external DNS resolution if overriding system DNS")
 	cmd.Flags().StringSliceVar(&opts.MatchDomains, "match-domains", nil, "FQDN wildcard patterns (e.g. '*.proxy.internal') to check against local records/upstream DNS; queries for non-matching domains go directly to the system's DNS servers (default: match all domains, or the value from config if set)")
 	cmd.Flags().BoolVar(&opts.PreferLocalRoutes, "prefer-local-routes", false, "Add tunnel routes with a high metric so overlapping local/connected routes take precedence (default false)")
+	cmd.Flags().BoolVar(&opts.Reconnect, "reconnect", true, "Restart the tunnel with backoff when the server terminates it or rejects the logged-in account's credentials, and rebind it when the network changes, instead of exiting")
+	cmd.Flags().StringVar(&opts.KillSwitch, "kill-switch", "off", "Block traffic to the organization's ranges (`mode` protected) or all traffic (all) unless it goes through the tunnel; kept in place if the client crashes")
+	cmd.Flags().Lookup("kill-switch").NoOptDefVal = string(killswitch.ModeProtected)
 	cmd.Flags().BoolVar(&opts.Attached, "attach", false, "Run in attached (foreground) mode, (default: detached (background) mode)")
 	cmd.Flags().BoolVar(&opts.Silent, "silent", false, "Disable TUI and run silently when detached")
+	cmd.Flags().StringVar(&opts.Instance, "instance", "", "Run as a separate named client `instance` with its own socket, interface and log file (default: the profile name, or the organization when another client is running)")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Use the settings of the named profile (see `pangolin config profile`)")
+	_ = cmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cfg := config.ConfigFromContext(cmd.Context())
+		if cfg == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return cfg.UpProfileNames(), cobra.ShellCompDirectiveNoFileComp
+	})
 
 	return cmd
 }
 
-// Precedence: flags > env/config > built-in defaults.
+// applyUpEnv reads credentials not given as flags from the environment, as
+// the client service and the container entrypoint provide them.
+func applyUpEnv(cmd *cobra.Command, opts *ClientUpCmdOpts) {
+	if !cmd.Flags().Changed("id") && !cmd.Flags().Changed("secret") && !cmd.Flags().Changed("secret-file") {
+		opts.ID = os.Getenv("CLIENT_ID")
+		opts.Secret = os.Getenv("CLIENT_SECRET")
+		if opts.Secret == "" {
+			opts.SecretFile = os.Getenv("CLIENT_SECRET_FILE")
+		}
+	}
+	if !cmd.Flags().Changed("endpoint") {
+		opts.Endpoint = os.Getenv("PANGOLIN_ENDPOINT")
+	}
+	if !cmd.Flags().Changed("org") {
+		opts.OrgID = os.Getenv("PANGOLIN_ORG")
+	}
+}
+
+// applyUpProfile sets each flag that was not passed explicitly from the named
+// profile. The profile's values then count as flags, so they take precedence
+// over the up.* config defaults and are forwarded to a detached subprocess.
+func applyUpProfile(cmd *cobra.Command, cfg *config.Config, name string) error {
+	if cfg == nil {
+		return errors.New("configuration not loaded")
+	}
+	values, ok := cfg.UpProfile(name)
+	if !ok {
+		return fmt.Errorf("profile %q not found; run `pangolin config profile list` to see the available profiles", name)
+	}
+	for _, opt := range config.UpProfileOptions {
+		value, ok := values[opt.Key]
+		if !ok || cmd.Flags().Changed(opt.Flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(opt.Flag, value); err != nil {
+			return fmt.Errorf("profile %q: %s: %w", name, opt.Key, err)
+		}
+	}
+	return nil
+}
+
+// Precedence: flags > profile > env/config > built-in defaults.
 func applyUpDefaults(cmd *cobra.Command, opts *ClientUpCmdOpts, cfg *config.Config) {
 	if cfg == nil {
 		return
@@ -161,6 +291,9 @@ func applyUpDefaults(cmd *cobra.Command, opts *ClientUpCmdOpts, cfg *config.Conf
 	if !cmd.Flags().Changed("prefer-local-routes") && cfg.IsSet("up.prefer_local_routes") {
 		opts.PreferLocalRoutes = cfg.GetBool("up.prefer_local_routes")
 	}
+	if !cmd.Flags().Changed("kill-switch") && cfg.IsSet("up.kill_switch") {
+		opts.KillSwitch = cfg.GetString("up.kill_switch")
+	}
 }
 
 func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string) error {
@@ -182,13 +315,41 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		return err
 	}
 
-	// Check if a client is already running
-	olmClient := olm.NewClient("")
-	if olmClient.IsRunning() {
-		err := errors.New("a client is already running")
+	killSwitchMode, err := killswitch.ParseMode(opts.KillSwitch)
+	if err != nil {
 		logger.Error("Error: %v", err)
 		return err
 	}
+	if killSwitchMode != killswitch.ModeOff && runtime.GOOS != "linux" {
+		logger.Error("Error: %v", killswitch.ErrUnsupported)
+		return killswitch.ErrUnsupported
+	}
+
+	// Each instance has its own socket, interface and log file. An
+	// instance is named explicitly or after the profile; otherwise the
+	// default instance is used unless it is already running, in which case
+	// the organization names a new one (see below).
+	instance := olm.InstanceName(opts.Instance)
+	if instance == "" {
+		instance = olm.InstanceName(opts.Profile)
+	}
+	defaultBusy := false
+	if olm.NewInstance(instance).Client().IsRunning() {
+		if instance != "" {
+			err := fmt.Errorf("client instance %q is already running", instance)
+			logger.Error("Error: %v", err)
+			return err
+		}
+		defaultBusy = true
+	}
+
+	// Let the init system start and supervise the client when it is
+	// installed as a service, rather than spawning one of our own.
+	if instance == "" && !defaultBusy && !opts.Attached && os.Getenv("PANGOLIN_SUBPROCESS") != "1" {
+		if m, ok := service.Installed(); ok {
+			return serviceUpMain(cmd, opts, m)
+		}
+	}
 
 	// Use provided flags whenever possible.
 	// No user session is needed when passing these directly,
@@ -197,6 +358,7 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 	olmSecret := opts.Secret
 
 	credentialsFromKeyring := olmID == "" && olmSecret == ""
+	userToken := opts.userToken
 
 	// Determine endpoint early
 	var endpoint string
@@ -276,6 +438,7 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 
 		olmID = activeAccount.OlmCredentials.ID
 		olmSecret = activeAccount.OlmCredentials.Secret
+		userToken = activeAccount.SessionToken
 	}
 
 	orgID := opts.OrgID
@@ -295,10 +458,44 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		orgID = activeAccount.OrgID
 	}
 
+	if defaultBusy {
+		running, err := olm.NewClient("").GetStatus()
+		if err != nil || running.OrgID == orgID || orgID == "" {
+			err := errors.New("a client is already running")
+			logger.Error("Error: %v", err)
+			logger.Info("Pass --instance [name] to run another client alongside it.")
+			return err
+		}
+		instance = olm.InstanceName(orgID)
+		if olm.NewInstance(instance).Client().IsRunning() {
+			err := fmt.Errorf("a client for organization %s is already running", orgID)
+			logger.Error("Error: %v", err)
+			return err
+		}
+		logger.Info("A client is already running for organization %s; starting instance %q for %s", running.OrgID, instance, orgID)
+	}
+
+	if instance != "" {
+		if !cmd.Flags().Changed("interface-name") {
+			opts.InterfaceName = olm.InstanceInterfaceName(instance)
+		}
+		// DNS overrides are system-wide, so by default only the first
+		// client installs one.
+		if !cmd.Flags().Changed("override-dns") && len(olm.RunningInstances()) > 0 {
+			_ = cmd.Flags().Set("override-dns", "false")
+			logger.Info("Another client is running; not overriding system DNS for instance %q (pass --override-dns to force)", instance)
+		}
+	}
+
+	if err := checkKillSwitchConflicts(killSwitchMode, opts.InterfaceName); err != nil {
+		logger.Error("Error: %v", err)
+		return err
+	}
+
 	// Handle log file setup - if detached mode, always use log file
 	var logFile string
 	if !opts.Attached {
-		logFile = cfg.LogFile
+		logFile = cfg.ClientLogFile(instance)
 	}
 
 	// Handle detached mode - subprocess self without --attach flag
@@ -321,11 +518,20 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		if orgID != "" {
 			cmdArgs = append(cmdArgs, "--org", orgID)
 		}
+		if instance != "" {
+			cmdArgs = append(cmdArgs, "--instance", instance)
+		}
 
-		// Add all flags that were set (except --attach)
-		// OLM credentials are always included (from flags, config, or newly created)
-		cmdArgs = append(cmdArgs, "--id", olmID)
-		cmdArgs = append(cmdArgs, "--secret", olmSecret)
+		// OLM credentials (from flags, config, or newly created) and the
+		// session token go through a handoff file that the subprocess
+		// removes once read, never on its command line.
+		handoffPath, err := writeHandoff(credentialHandoff{ID: olmID, Secret: olmSecret, UserToken: userToken})
+		if err != nil {
+			logger.Error("Error: %v", err)
+			return err
+		}
+		defer os.Remove(handoffPath)
+		cmdArgs = append(cmdArgs, "--credentials-file", handoffPath)
 
 		// Always pass endpoint to subprocess (required, subprocess won't have user's config)
 		// Get endpoint from flag or hostname config (same logic as attached mode)
@@ -360,6 +566,13 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 				cmdArgs = append(cmdArgs, "--holepunch=false")
 			}
 		}
+		if cmd.Flags().Changed("reconnect") {
+			if opts.Reconnect {
+				cmdArgs = append(cmdArgs, "--reconnect")
+			} else {
+				cmdArgs = append(cmdArgs, "--reconnect=false")
+			}
+		}
 		if cmd.Flags().Changed("tls-client-cert") {
 			cmdArgs = append(cmdArgs, "--tls-client-cert", opts.TlsClientCert)
 		}
@@ -392,6 +605,10 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 			// same reason as MatchDomains above - it may have come from config.
 			cmdArgs = append(cmdArgs, "--prefer-local-routes")
 		}
+		if killSwitchMode != killswitch.ModeOff {
+			// Always forwarded, as it may have come from config too.
+			cmdArgs = append(cmdArgs, "--kill-switch="+string(killSwitchMode))
+		}
 
 		// Add positional args if any
 		cmdArgs = append(cmdArgs, extraArgs...)
@@ -450,72 +667,21 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 			return err
 		}
 
-		// In silent mode, skip TUI and just exit after starting the process
-		if opts.Silent {
-			return nil
-		}
-
-		// Show live log preview and status
-		completed, statusError, err := tui.NewLogPreview(tui.LogPreviewConfig{
-			LogFile: logFile,
-			Header:  "Starting up client...",
-			ExitCondition: func(client *olm.Client, status *olm.StatusResponse) (bool, bool) {
-				// Exit when both connected and registered
-				if status != nil && status.Connected && status.Registered {
-					return true, true
-				}
-				// Exit on error before registration (handled in statusUpdateMsg)
-				return false, false
-			},
-			OnEarlyExit: func(client *olm.Client) {
-				// Kill the subprocess if user exits early
-				if client.IsRunning() {
-					_, _ = client.Exit()
-				}
-			},
-			OnError: func(client *olm.Client, statusError *olm.StatusError) {
-				// Stop the client on error (error will be printed after TUI exits)
-				if client.IsRunning() {
-					_, _ = client.Exit()
-				}
-			},
-			StatusFormatter: func(isRunning bool, status *olm.StatusResponse) string {
-				if !isRunning || status == nil {
-					return "Starting"
-				}
-				// Show error if present
-				if status.Error != nil {
-					return fmt.Sprintf("Error: %s", status.Error.Message)
-				}
-				// Status is only "Connected" when both connected and registered
-				if status.Connected && status.Registered {
-					return "Connected"
-				} else if status.Registered {
-					return "Registered"
-				}
-				return "Starting"
-			},
-		})
-		if err != nil {
+		// The subprocess reads the handoff file during startup; if it never
+		// does, it failed before it could connect.
+		if !waitForHandoff(handoffPath, handoffTimeout) {
+			err := errors.New("the client did not start")
 			logger.Error("Error: %v", err)
+			logger.Info("Run `pangolin logs client` or rerun with --attach to see why.")
 			return err
 		}
 
-		// Print error after TUI exits if there was one
-		if statusError != nil {
-			logger.Error("Connection error: %s", statusError.Message)
-			return fmt.Errorf("connection failed: %s", statusError.Message)
+		// In silent mode, skip TUI and just exit after starting the process
+		if opts.Silent {
+			return nil
 		}
 
-		// Check if the process completed successfully or was killed
-		if !completed {
-			// User exited early - subprocess was killed
-			logger.Info("Client process killed")
-		} else {
-			// Completed successfully
-			logger.Success("Client interface created successfully")
-		}
-		return nil
+		return previewClientStart(logFile, olm.InstanceSocketPath(instance))
 	}
 
 	enableAPI := defaultEnableAPI
@@ -525,7 +691,8 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		enableAPI = true
 	}
 
-	socketPath := defaultSocketPath
+	socketPath := olm.InstanceSocketPath(instance)
+	reconnectStatePath := olm.ReconnectStatePath(cfg.EventJournalDir(), instance)
 
 	upstreamDNS := make([]string, 0, len(opts.UpstreamDNS))
 	for _, server := range opts.UpstreamDNS {
@@ -543,34 +710,102 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 
 	// Setup log file if specified
 	if logFile != "" {
-		if err := setupLogFile(cfg.LogFile); err != nil {
+		if err := setupLogFile(logFile); err != nil {
 			logger.Error("Error: failed to setup log file: %v", err)
 			return err
 		}
 	}
 
-	// Get UserToken from config if credentials came from config
+	// Get UserToken from config if credentials came from config and the
+	// parent process did not hand it over with them.
 	// Check environment variable to distinguish between:
 	// - Parent process passing id/secret from config (should fetch userToken)
 	// - User directly passing id/secret (should NOT fetch userToken)
-	var userToken string
 	credentialsFromKeyringEnv := os.Getenv("PANGOLIN_CREDENTIALS_FROM_KEYRING")
 	credentialsFromKeyring = credentialsFromKeyringEnv == "1" || credentialsFromKeyring
-	if credentialsFromKeyring {
+	if credentialsFromKeyring && userToken == "" {
 		// Credentials came from config, fetch userToken from secrets
+		// The session token may live in a secret backend that is not
+		// reachable from this (root) process, such as the user's keyring.
+		// The tunnel still works without it, so only warn.
 		activeAccount, err := accountStore.ActiveAccount()
 		if err != nil {
-			logger.Error("Failed to get session token: %v", err)
-			return err
+			logger.Warning("Failed to get session token: %v", err)
+		} else {
+			userToken = activeAccount.SessionToken
 		}
+	}
 
-		userToken = activeAccount.SessionToken
+	// With credentials from the account, the supervisor can replace
+	// credentials the server no longer accepts, and restarts the tunnel
+	// with the session token of the account as it is stored now, in case
+	// the user logged in again since the client started.
+	var refreshCredentials func() (string, string, error)
+	var currentUserToken func() string
+	if credentialsFromKeyring {
+		var userID string
+		if activeAccount, err := accountStore.ActiveAccount(); err == nil {
+			userID = activeAccount.UserID
+		}
+		currentUserToken = func() string {
+			_, store, err := companion.Resolve(cfg)
+			if err != nil {
+				return ""
+			}
+			activeAccount, err := store.ActiveAccount()
+			if err != nil || activeAccount.UserID != userID {
+				return ""
+			}
+			return activeAccount.SessionToken
+		}
+		refreshCredentials = func() (string, string, error) {
+			activeAccount, err := accountStore.ActiveAccount()
+			if err != nil {
+				return "", "", err
+			}
+			newCredsGenerated, err := utils.EnsureOlmCredentials(apiClient, activeAccount)
+			if err != nil {
+				return "", "", err
+			}
+			if newCredsGenerated {
+				if err := accountStore.UpdateActiveAccount(activeAccount); err != nil {
+					return "", "", err
+				}
+				if err := accountStore.Save(); err != nil {
+					return "", "", err
+				}
+			}
+			return activeAccount.OlmCredentials.ID, activeAccount.OlmCredentials.Secret, nil
+		}
 	}
 
 	// Create context for signal handling and cleanup
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// waitEvents blocks until the event journal has recorded the client
+	// stopping. It is set before olm starts calling back, and not changed
+	// after.
+	waitEvents := func() {}
+
+	// beforeExit runs when a callback ends the process, skipping the
+	// deferred cleanup: it records the stop and runs the down hooks.
+	hooks := newHookRunner(cfg, olm.NewInstance(instance).DisplayName(), orgID, opts.InterfaceName)
+	beforeExit := func() {
+		hooks.runOnce(hookPreDown)
+		waitEvents()
+		hooks.runOnce(hookPostDown)
+	}
+
+	// sup restarts the tunnel instead of exiting when it is terminated; it
+	// is nil with --reconnect=false.
+	var sup *supervisor
+
+	// ks keeps the kill switch rules up to date; it is nil when the kill
+	// switch is off. The rules are only removed when the client is stopped
+	// on purpose, so they stay in place when it fails.
+	var ks *killSwitch
+
 	// Create OLM GlobalConfig with hardcoded values from Swift
 	olmInitConfig := olmpkg.OlmConfig{
 		LogLevel:   opts.LogLevel,
@@ -583,19 +818,49 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		// override is installed. The watchdog will reset DNS if this
 		// process dies before it can restore the original configuration.
 		WatchdogSubcommand: []string{"watchdog"},
-		WatchdogLogFile:    cfg.LogFile,
+		WatchdogLogFile:    cfg.ClientLogFile(instance),
+		OnConnected: func() {
+			if sup != nil {
+				sup.onConnected()
+			}
+		},
 		OnTerminated: func() {
+			if sup != nil {
+				sup.onTerminated()
+				return
+			}
 			logger.Info("Client process terminated")
+			if ks != nil {
+				logger.Warning("Kill switch left in place; run `sudo pangolin reset-dns` to remove it")
+			}
 			stop()
+			beforeExit()
 			os.Exit(0)
 		},
 		OnAuthError: func(statusCode int, message string) {
 			logger.Error("Authentication error: %d %s", statusCode, message)
+			// Credentials given with --id/--secret, CLIENT_SECRET or the
+			// service's environment file cannot be replaced, so retrying
+			// them is pointless. Exit with an error instead and leave
+			// restarting to the service manager.
+			if sup != nil && sup.canRefresh() {
+				sup.onAuthError(statusCode, message)
+				return
+			}
+			if ks != nil {
+				logger.Warning("Kill switch left in place; run `sudo pangolin reset-dns` to remove it")
+			}
 			stop()
+			beforeExit()
 			os.Exit(1)
 		},
 		OnExit: func() {
 			logger.Info("Client process exiting")
+			if ks != nil {
+				ks.disable()
+			}
+			stop()
+			beforeExit()
 			os.Exit(0)
 		},
 	}
@@ -649,11 +914,42 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 		}
 	}
 
+	if opts.Reconnect {
+		sup = newSupervisor(tunnelConfig, reconnectStatePath, refreshCredentials, currentUserToken)
+	}
+
+	if err := hooks.run(hookPreUp); err != nil {
+		logger.Error("Error: %v", err)
+		return err
+	}
+
+	if killSwitchMode != killswitch.ModeOff {
+		if err := killswitch.ValidateInterface(opts.InterfaceName); err != nil {
+			logger.Error("Error: %v", err)
+			return err
+		}
+		ks = newKillSwitch(killSwitchMode, opts.InterfaceName, endpoint, killswitch.StatePath(opts.InterfaceName))
+		if err := ks.enable(); err != nil {
+			logger.Error("Error: failed to enable the kill switch: %v", err)
+			return err
+		}
+		logger.Info("Kill switch enabled (%s)", killSwitchMode)
+	}
+
+	if enableAPI {
+		// The journal polls the API until it is up.
+		waitEvents = startEventJournal(ctx, cfg.EventJournalDir(), instance, socketPath, hooks.queue())
+	}
+
 	olm, err := olmpkg.Init(ctx, olmInitConfig)
 	if err != nil {
 		logger.Error("Error: failed to init olm: %v", err)
 		return err
 	}
+	if sup != nil {
+		sup.olm = olm
+	}
+	defer hooks.runOnce(hookPostDown)
 	defer olm.Close()
 
 	// Only run ongoing fingerprint updates for user devices
@@ -670,13 +966,161 @@ func clientUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, extraArgs []string)
 	// without causing the CLI process to exit
 	go olm.StartTunnel(tunnelConfig)
 
+	if sup != nil {
+		go sup.run(ctx)
+	}
+
+	if ks != nil {
+		go ks.run(ctx, olm)
+		// The client only spawns the watchdog along with a DNS override;
+		// without one, spawn it here so it reinstates the kill switch.
+		if !opts.OverrideDNS {
+			watchdogCmd, err := startKillSwitchWatchdog(opts.InterfaceName, socketPath, cfg.ClientLogFile(instance))
+			if err != nil {
+				logger.Warning("The kill switch will not be reinstated if the client crashes: %v", err)
+			} else {
+				defer dnsOverride.StopWatchdog(watchdogCmd)
+			}
+		}
+	}
+
 	// Block on context to keep process alive
 	<-ctx.Done()
 	logger.Info("Received shutdown signal, stopping tunnel")
+	hooks.runOnce(hookPreDown)
+	waitEvents()
+	if ks != nil {
+		ks.disable()
+	}
 
 	return nil
 }
 
+// startEventJournal records the client's connection events, derived from
+// its status, in the instance's journal until ctx is done, and passes each
+// to onEvent. The returned function waits for the final event.
+func startEventJournal(ctx context.Context, dir, instance, socketPath string, onEvent func(events.Event)) func() {
+	journal, err := events.OpenJournal(events.JournalPath(dir, instance))
+	if err != nil {
+		logger.Warning("Connection events will not be recorded: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		events.Watch(ctx, olm.NewClient(socketPath), olm.NewInstance(instance).DisplayName(), eventPollInterval, func(e events.Event) {
+			if journal != nil {
+				if err := journal.Append(e); err != nil {
+					logger.Debug("Failed to record event: %v", err)
+				}
+			}
+			onEvent(e)
+		})
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+			}
+		})
+	}
+}
+
+// serviceUpMain starts the installed client service. The service runs with
+// the settings it was installed with, so flags given here are ignored.
+func serviceUpMain(cmd *cobra.Command, opts *ClientUpCmdOpts, m service.Manager) error {
+	ignored := cmd.Flags().NFlag()
+	if cmd.Flags().Changed("silent") {
+		ignored--
+	}
+	if ignored > 0 {
+		logger.Warning("The client service runs with the settings it was installed with; flags passed to this command are ignored")
+		logger.Info("Run `sudo pangolin service install` with new settings to change them.")
+	}
+
+	if err := m.Start(); err != nil {
+		logger.Error("Error: failed to start the client service: %v", err)
+		return err
+	}
+
+	if opts.Silent {
+		return nil
+	}
+
+	// The service logs to the journal rather than the log file.
+	return previewClientStart("", olm.InstanceSocketPath(""))
+}
+
+// previewClientStart shows the client's log and status until it has
+// connected, stopping the client if it fails or the user exits early.
+func previewClientStart(logFile, socketPath string) error {
+	// Show live log preview and status
+	completed, statusError, err := tui.NewLogPreview(tui.LogPreviewConfig{
+		LogFile:    logFile,
+		SocketPath: socketPath,
+		Header:     "Starting up client...",
+		ExitCondition: func(client *olm.Client, status *olm.StatusResponse) (bool, bool) {
+			// Exit when both connected and registered
+			if status != nil && status.Connected && status.Registered {
+				return true, true
+			}
+			// Exit on error before registration (handled in statusUpdateMsg)
+			return false, false
+		},
+		OnEarlyExit: func(client *olm.Client) {
+			// Kill the subprocess if user exits early
+			if client.IsRunning() {
+				_, _ = client.Exit()
+			}
+		},
+		OnError: func(client *olm.Client, statusError *olm.StatusError) {
+			// Stop the client on error (error will be printed after TUI exits)
+			if client.IsRunning() {
+				_, _ = client.Exit()
+			}
+		},
+		StatusFormatter: func(isRunning bool, status *olm.StatusResponse) string {
+			if !isRunning || status == nil {
+				return "Starting"
+			}
+			// Show error if present
+			if status.Error != nil {
+				return fmt.Sprintf("Error: %s", status.Error.Message)
+			}
+			// Status is only "Connected" when both connected and registered
+			if status.Connected && status.Registered {
+				return "Connected"
+			} else if status.Registered {
+				return "Registered"
+			}
+			return "Starting"
+		},
+	})
+	if err != nil {
+		logger.Error("Error: %v", err)
+		return err
+	}
+
+	// Print error after TUI exits if there was one
+	if statusError != nil {
+		logger.Error("Connection error: %s", statusError.Message)
+		return fmt.Errorf("connection failed: %s", statusError.Message)
+	}
+
+	// Check if the process completed successfully or was killed
+	if !completed {
+		// User exited early - subprocess was killed
+		logger.Info("Client process killed")
+	} else {
+		// Completed successfully
+		logger.Success("Client interface created successfully")
+	}
+	return nil
+}
+
 // setupLogFile sets up file logging with rotation
 func setupLogFile(logPath string) error {
 	logDir := filepath.Dir(logPath)
@@ -722,7 +1166,8 @@ func rotateLogFile(logDir string, logFile string) error {
 	}
 
 	// Create rotated filename with date
-	rotatedName := fmt.Sprintf("client-%s.log", fileTime.Format("2006-01-02"))
+	base := strings.TrimSuffix(filepath.Base(logFile), ".log")
+	rotatedName := fmt.Sprintf("%s-%s.log", base, fileTime.Format("2006-01-02"))
 	rotatedPath := filepath.Join(logDir, rotatedName)
 
 	// Rename current log file to dated filename
@@ -732,12 +1177,14 @@ func rotateLogFile(logDir string, logFile string) error {
 	}
 
 	// Clean up old log files (keep last 30 days)
-	cleanupOldLogFiles(logDir, 30)
+	cleanupOldLogFiles(logDir, base, 30)
 	return nil
 }
 
-// cleanupOldLogFiles removes log files older than specified days
-func cleanupOldLogFiles(logDir string, daysToKeep int) {
+// cleanupOldLogFiles removes the files rotated from the log named base
+// (base-YYYY-MM-DD.log) that are older than specified days. Other logs in
+// the directory, such as those of other instances, are left alone.
+func cleanupOldLogFiles(logDir string, base string, daysToKeep int) {
 	cutoff := time.Now().AddDate(0, 0, -daysToKeep)
 	files, err := os.ReadDir(logDir)
 	if err != nil {
@@ -745,7 +1192,7 @@ func cleanupOldLogFiles(logDir string, daysToKeep int) {
 	}
 
 	for _, file := range files {
-		if !file.IsDir() && strings.HasPrefix(file.Name(), "client-") && strings.HasSuffix(file.Name(), ".log") {
+		if !file.IsDir() && isRotatedLog(file.Name(), base) {
 			filePath := filepath.Join(logDir, file.Name())
 			info, err := file.Info()
 			if err != nil {
@@ -758,6 +1205,21 @@ func cleanupOldLogFiles(logDir string, daysToKeep int) {
 	}
 }
 
+// isRotatedLog reports whether name is a log rotated from base by
+// rotateLogFile.
+func isRotatedLog(name, base string) bool {
+	date, ok := strings.CutPrefix(name, base+"-")
+	if !ok {
+		return false
+	}
+	date, ok = strings.CutSuffix(date, ".log")
+	if !ok {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
+
 func startFingerprinting(o *olmpkg.Olm) context.CancelFunc {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// credentialHandoff carries the credentials the detached client needs. They
+// are passed in a one-time file rather than on its command line, where any
+// local user could read them from ps or /proc/<pid>/cmdline.
+type credentialHandoff struct {
+	ID        string `json:"id"`
+	Secret    string `json:"secret"`
+	UserToken string `json:"userToken,omitempty"`
+}
+
+// writeHandoff writes h to a new file readable only by the current user and
+// returns its path. The elevated child removes it once read.
+func writeHandoff(h credentialHandoff) (string, error) {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+
+	// CreateTemp creates the file with mode 0600.
+	f, err := os.CreateTemp("", "pangolin-handoff-*")
+	if err != nil {
+		return "", fmt.Errorf("create credentials handoff file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write credentials handoff file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write credentials handoff file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// readHandoff reads and removes the handoff file at path.
+func readHandoff(path string) (credentialHandoff, error) {
+	var h credentialHandoff
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return h, fmt.Errorf("credentials handoff file: %w", err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0o077 != 0 {
+		return h, fmt.Errorf("credentials handoff file %s must be a regular file readable only by its owner", path)
+	}
+
+	data, err := os.ReadFile(path)
+	_ = os.Remove(path)
+	if err != nil {
+		return h, fmt.Errorf("credentials handoff file: %w", err)
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("credentials handoff file: %w", err)
+	}
+	if h.ID == "" || h.Secret == "" {
+		return h, errors.New("credentials handoff file is missing the client ID or secret")
+	}
+	return h, nil
+}
+
+// waitForHandoff waits for the child to consume the handoff file at path and
+// removes it itself if that does not happen within timeout.
+func waitForHandoff(path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	_ = os.Remove(path)
+	return false
+}
+
+// readSecretFile returns the secret stored in path without surrounding
+// whitespace.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read secret file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
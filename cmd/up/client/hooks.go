@@ -0,0 +1,178 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/events"
+	newtLogger "github.com/fosrl/newt/logger"
+)
+
+// Lifecycle hooks, named as in the hooks.* config keys.
+const (
+	hookPreUp        = "pre_up"
+	hookPostUp       = "post_up"
+	hookPreDown      = "pre_down"
+	hookPostDown     = "post_down"
+	hookOnPeerChange = "on_peer_change"
+)
+
+// hookRunner runs the lifecycle hooks configured for one client. Hooks run
+// one at a time; their output goes to the client log.
+type hookRunner struct {
+	commands map[string]string
+	timeout  time.Duration
+	env      []string
+
+	mu   sync.Mutex
+	done map[string]bool
+}
+
+// newHookRunner returns the runner for the hooks in the hooks file. Because
+// hooks usually run as root, they are ignored when the file could have been
+// changed by another user.
+func newHookRunner(cfg *config.Config, instance, orgID, interfaceName string) *hookRunner {
+	hooks, err := config.LoadHooks()
+	if err != nil {
+		newtLogger.Warn("Ignoring hooks: %v", err)
+	}
+	if cfg.IsSet("hooks") {
+		newtLogger.Warn("Ignoring hooks in the config file; they are read from %s", config.HooksFilePath())
+	}
+	return &hookRunner{
+		commands: hooks.Commands(),
+		timeout:  hooks.TimeoutDuration(),
+		env: []string{
+			"PANGOLIN_INSTANCE=" + instance,
+			"PANGOLIN_ORG_ID=" + orgID,
+			"PANGOLIN_INTERFACE=" + interfaceName,
+		},
+		done: map[string]bool{},
+	}
+}
+
+// run runs the named hook, if configured, with extra environment variables
+// in KEY=value form, and waits for it to finish.
+func (h *hookRunner) run(name string, extra ...string) error {
+	command := h.commands[name]
+	if command == "" {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	c.Env = append(os.Environ(), h.env...)
+	c.Env = append(c.Env, "PANGOLIN_HOOK="+name)
+	if !hasEnv(extra, "PANGOLIN_EVENT") {
+		c.Env = append(c.Env, "PANGOLIN_EVENT="+name)
+	}
+	c.Env = append(c.Env, extra...)
+
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	// Do not wait on background processes the hook left holding its output.
+	c.WaitDelay = time.Second
+
+	newtLogger.Info("Running %s hook", name)
+	start := time.Now()
+	err := c.Run()
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		if line != "" {
+			newtLogger.Info("[%s] %s", name, line)
+		}
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%s hook timed out after %s", name, h.timeout)
+	} else if err != nil {
+		err = fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	if err != nil {
+		newtLogger.Error("%v", err)
+		return err
+	}
+	newtLogger.Info("%s hook finished in %s", name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runOnce runs the named hook unless it already ran, for hooks that more
+// than one shutdown path may reach.
+func (h *hookRunner) runOnce(name string) {
+	h.mu.Lock()
+	ran := h.done[name]
+	h.done[name] = true
+	h.mu.Unlock()
+	if !ran {
+		_ = h.run(name)
+	}
+}
+
+// queue returns a function that hands events to onEvent in the background,
+// so that slow hooks do not hold up the event journal. Events that arrive
+// while many are pending are dropped.
+func (h *hookRunner) queue() func(events.Event) {
+	if len(h.commands) == 0 {
+		return func(events.Event) {}
+	}
+	pending := make(chan events.Event, 64)
+	go func() {
+		for e := range pending {
+			h.onEvent(e)
+		}
+	}()
+	return func(e events.Event) {
+		select {
+		case pending <- e:
+		default:
+			newtLogger.Warn("Skipping hooks for %s event: too many pending", e.Type)
+		}
+	}
+}
+
+// onEvent runs the hooks that follow connection events: post_up each time
+// the tunnel connects, and on_peer_change when a site is added, removed,
+// goes up or down, or changes path.
+func (h *hookRunner) onEvent(e events.Event) {
+	switch e.Type {
+	case events.Connected:
+		_ = h.run(hookPostUp)
+	case events.PeerAdded, events.PeerRemoved, events.PeerUp, events.PeerDown, events.PeerPathChanged:
+		_ = h.run(hookOnPeerChange,
+			"PANGOLIN_EVENT="+e.Type,
+			"PANGOLIN_SITE_ID="+strconv.Itoa(e.SiteID),
+			"PANGOLIN_SITE_NAME="+e.Site,
+			"PANGOLIN_PEER_PATH="+e.To,
+			"PANGOLIN_PEER_PREVIOUS_PATH="+e.From,
+		)
+	}
+}
+
+func hasEnv(env []string, key string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, key+"=") {
+			return true
+		}
+	}
+	return false
+}
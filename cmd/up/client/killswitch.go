@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/fosrl/cli/internal/killswitch"
+	"github.com/fosrl/cli/internal/olm"
+	newtLogger "github.com/fosrl/newt/logger"
+	"github.com/fosrl/newt/network"
+	dnsOverride "github.com/fosrl/olm/dns/override"
+	olmpkg "github.com/fosrl/olm/olm"
+)
+
+const killSwitchSyncInterval = 5 * time.Second
+
+// checkKillSwitchConflicts refuses kill switches that would cut off another
+// client's tunnel. Each client's rules accept only its own interface and
+// server, so one with --kill-switch=all drops the traffic of every other
+// tunnel on the host.
+func checkKillSwitchConflicts(mode killswitch.Mode, iface string) error {
+	if mode == killswitch.ModeAll && len(olm.RunningInstances()) > 0 {
+		return errors.New("--kill-switch=all would block the tunnels of the other running clients; stop them first or use --kill-switch=protected")
+	}
+	paths, err := killswitch.StatePaths()
+	if err != nil {
+		return nil
+	}
+	for _, path := range paths {
+		rules, err := killswitch.Load(path)
+		if err != nil || rules == nil {
+			continue
+		}
+		if rules.Mode == killswitch.ModeAll && rules.Interface != iface {
+			return fmt.Errorf("the client on interface %s has --kill-switch=all, which blocks other tunnels; stop it first, or run `sudo pangolin reset-dns` if it is no longer running", rules.Interface)
+		}
+	}
+	return nil
+}
+
+// killSwitch keeps the kill switch rules of the client's interface in step
+// with the tunnel's routes and the endpoints it talks to. The rules are
+// recorded so the watchdog can reinstate them after a crash.
+type killSwitch struct {
+	rules      killswitch.Rules
+	statePath  string
+	serverHost string
+	serverIPs  []netip.Prefix
+}
+
+func newKillSwitch(mode killswitch.Mode, iface, endpoint, statePath string) *killSwitch {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return &killSwitch{
+		rules:      killswitch.Rules{Interface: iface, Mode: mode},
+		statePath:  statePath,
+		serverHost: host,
+	}
+}
+
+// enable installs the rules before the tunnel is up. Until the tunnel
+// reports its routes, the ranges recorded by the previous run stay
+// protected.
+func (k *killSwitch) enable() error {
+	if prev, err := killswitch.Load(k.statePath); err != nil {
+		newtLogger.Warn("Ignoring the previous kill switch state: %v", err)
+	} else if prev != nil && prev.Interface == k.rules.Interface {
+		k.rules.Protected = prev.Protected
+		k.rules.Allowed = prev.Allowed
+	}
+	k.resolveServer()
+	k.rules.Allowed = append(k.rules.Allowed, k.serverIPs...)
+	return k.apply(k.rules)
+}
+
+// run updates the rules from the tunnel's status until ctx is done. While
+// the tunnel is not registered the last rules are kept.
+func (k *killSwitch) run(ctx context.Context, o *olmpkg.Olm) {
+	ticker := time.NewTicker(killSwitchSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		status := o.GetStatus()
+		if !status.Registered {
+			continue
+		}
+		k.resolveServer()
+		rules := k.rules
+		rules.Protected, rules.Allowed = tunnelRoutes(status.NetworkSettings)
+		rules.Allowed = append(rules.Allowed, k.serverIPs...)
+		for _, peer := range status.PeerStatuses {
+			if ap, err := netip.ParseAddrPort(peer.Endpoint); err == nil {
+				rules.Allowed = append(rules.Allowed, netip.PrefixFrom(ap.Addr(), ap.Addr().BitLen()))
+			}
+		}
+		if rules.Equal(k.rules) {
+			continue
+		}
+		if err := k.apply(rules); err != nil {
+			newtLogger.Warn("Failed to update the kill switch: %v", err)
+		}
+	}
+}
+
+// disable removes the rules when the client is stopped on purpose.
+func (k *killSwitch) disable() {
+	if _, err := killswitch.Remove(k.rules.Interface); err != nil {
+		newtLogger.Warn("Failed to remove the kill switch: %v", err)
+		return
+	}
+	_ = os.Remove(k.statePath)
+	newtLogger.Info("Kill switch removed")
+}
+
+func (k *killSwitch) apply(rules killswitch.Rules) error {
+	if err := killswitch.Apply(rules); err != nil {
+		return err
+	}
+	k.rules = rules
+	if err := killswitch.Save(k.statePath, rules); err != nil {
+		newtLogger.Warn("Failed to record the kill switch state: %v", err)
+	}
+	return nil
+}
+
+// resolveServer refreshes the server's addresses, keeping the last ones
+// when the lookup fails, as it will once DNS is blocked.
+func (k *killSwitch) resolveServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", k.serverHost)
+	if err != nil || len(addrs) == 0 {
+		return
+	}
+	ips := make([]netip.Prefix, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		ips = append(ips, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	k.serverIPs = ips
+}
+
+// tunnelRoutes returns the ranges routed through the tunnel and those
+// excluded from it.
+func tunnelRoutes(settings network.NetworkSettings) (included, excluded []netip.Prefix) {
+	for _, route := range settings.IPv4IncludedRoutes {
+		if p, ok := ipv4Route(route); ok {
+			included = append(included, p)
+		}
+	}
+	for _, route := range settings.IPv4ExcludedRoutes {
+		if p, ok := ipv4Route(route); ok {
+			excluded = append(excluded, p)
+		}
+	}
+	for _, route := range settings.IPv6IncludedRoutes {
+		if p, ok := ipv6Route(route); ok {
+			included = append(included, p)
+		}
+	}
+	for _, route := range settings.IPv6ExcludedRoutes {
+		if p, ok := ipv6Route(route); ok {
+			excluded = append(excluded, p)
+		}
+	}
+	return included, excluded
+}
+
+// ipv4Route converts a destination and dotted subnet mask; without a mask
+// the destination is a single host.
+func ipv4Route(route network.IPv4Route) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(route.DestinationAddress)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	bits := 32
+	switch m := net.ParseIP(route.SubnetMask).To4(); {
+	case route.IsDefault:
+		bits = 0
+	case m != nil:
+		var size int
+		if bits, size = net.IPMask(m).Size(); size == 0 {
+			return netip.Prefix{}, false
+		}
+	}
+	return netip.PrefixFrom(addr, bits), true
+}
+
+func ipv6Route(route network.IPv6Route) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(route.DestinationAddress)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	bits := route.NetworkPrefixLength
+	if bits == 0 && !route.IsDefault {
+		bits = 128
+	}
+	return netip.PrefixFrom(addr, bits), true
+}
+
+// startKillSwitchWatchdog spawns the watchdog the client would spawn with a
+// DNS override, which reinstates the kill switch if this process dies.
+func startKillSwitchWatchdog(iface, socketPath, logFile string) (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return dnsOverride.SpawnWatchdog(dnsOverride.SpawnWatchdogConfig{
+		Executable:    executable,
+		Subcommand:    []string{"watchdog"},
+		InterfaceName: iface,
+		SocketPath:    socketPath,
+		LogFile:       logFile,
+	})
+}
@@ -0,0 +1,259 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fosrl/cli/internal/netwatch"
+	"github.com/fosrl/cli/internal/olm"
+	newtLogger "github.com/fosrl/newt/logger"
+	olmpkg "github.com/fosrl/olm/olm"
+)
+
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 5 * time.Minute
+)
+
+// backoff doubles the delay between attempts up to a limit, with jitter so
+// that clients cut off together do not reconnect together.
+type backoff struct {
+	min, max time.Duration
+	next     time.Duration
+}
+
+func (b *backoff) Next() time.Duration {
+	if b.next == 0 {
+		b.next = b.min
+	}
+	d := b.next
+	b.next = min(b.next*2, b.max)
+	return d + rand.N(d/5+1)
+}
+
+func (b *backoff) Reset() {
+	b.next = 0
+}
+
+// supervisor restarts the tunnel when the server terminates it or rejects
+// credentials it can replace, instead of letting the client exit. Its progress is
+// written to a state file that `pangolin status` reads.
+type supervisor struct {
+	olm       *olmpkg.Olm
+	config    olmpkg.TunnelConfig
+	statePath string
+
+	// refresh returns valid client credentials, creating new ones if the
+	// current ones were revoked. It is nil when the credentials were given
+	// on the command line.
+	refresh func() (id, secret string, err error)
+
+	// userToken returns the account's current session token. It is nil
+	// when the credentials were given on the command line.
+	userToken func() string
+
+	failures chan string
+	wake     chan struct{}
+
+	mu           sync.Mutex
+	state        olm.ReconnectState
+	backoff      backoff
+	authRejected bool
+	connected    bool
+}
+
+func newSupervisor(config olmpkg.TunnelConfig, statePath string, refresh func() (string, string, error), userToken func() string) *supervisor {
+	return &supervisor{
+		config:    config,
+		statePath: statePath,
+		refresh:   refresh,
+		userToken: userToken,
+		failures:  make(chan string, 1),
+		wake:      make(chan struct{}, 1),
+		backoff:   backoff{min: reconnectMinDelay, max: reconnectMaxDelay},
+	}
+}
+
+// onConnected records that the tunnel is up again.
+func (s *supervisor) onConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.Reconnecting {
+		newtLogger.Info("Reconnected after %d attempt(s)", s.state.Attempt)
+	}
+	s.connected = true
+	s.backoff.Reset()
+	s.state = olm.ReconnectState{}
+	s.save()
+}
+
+// onTerminated schedules a restart. The client reports an authentication
+// error as a termination too, so a restart is scheduled once per failure.
+func (s *supervisor) onTerminated() {
+	s.failed("terminated by the server")
+}
+
+// canRefresh reports whether rejected credentials can be replaced.
+func (s *supervisor) canRefresh() bool {
+	return s.refresh != nil
+}
+
+// onAuthError schedules a restart with refreshed credentials.
+func (s *supervisor) onAuthError(statusCode int, message string) {
+	s.mu.Lock()
+	s.authRejected = true
+	s.mu.Unlock()
+	s.failed(fmt.Sprintf("authentication failed (%d %s)", statusCode, message))
+}
+
+func (s *supervisor) failed(reason string) {
+	s.mu.Lock()
+	s.connected = false
+	s.mu.Unlock()
+	select {
+	case s.failures <- reason:
+	default:
+	}
+}
+
+// onNetworkChange retries at once when waiting to reconnect, and otherwise
+// moves the tunnel's UDP socket to the new network.
+func (s *supervisor) onNetworkChange() {
+	s.mu.Lock()
+	reconnecting, connected := s.state.Reconnecting, s.connected
+	s.mu.Unlock()
+
+	switch {
+	case reconnecting:
+		newtLogger.Info("Network changed; retrying the connection now")
+		s.mu.Lock()
+		s.backoff.Reset()
+		s.mu.Unlock()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	case connected:
+		newtLogger.Info("Network changed; rebinding the tunnel socket")
+		if err := s.olm.RebindSocket(); err != nil {
+			newtLogger.Warn("Failed to rebind the tunnel socket: %v", err)
+		}
+	}
+}
+
+// run restarts the tunnel after each failure until ctx is done.
+func (s *supervisor) run(ctx context.Context) {
+	defer os.Remove(s.statePath)
+
+	if err := netwatch.Watch(ctx, s.config.InterfaceName, s.onNetworkChange); err != nil {
+		newtLogger.Warn("Network changes will not be detected: %v", err)
+	}
+
+	for {
+		var reason string
+		select {
+		case <-ctx.Done():
+			return
+		case reason = <-s.failures:
+		}
+		newtLogger.Warn("Tunnel stopped: %s; reconnecting", reason)
+
+		s.mu.Lock()
+		if !s.state.Reconnecting {
+			s.state = olm.ReconnectState{Reconnecting: true, Since: time.Now()}
+		}
+		s.state.Reason = reason
+		s.mu.Unlock()
+
+		if !s.restart(ctx) {
+			return
+		}
+	}
+}
+
+// restart waits out the backoff and starts the tunnel again, retrying for as
+// long as fresh credentials cannot be obtained. It returns false when ctx is
+// done.
+func (s *supervisor) restart(ctx context.Context) bool {
+	for {
+		s.mu.Lock()
+		s.state.Attempt++
+		attempt := s.state.Attempt
+		delay := s.backoff.Next()
+		s.state.NextAttempt = time.Now().Add(delay)
+		s.save()
+		s.mu.Unlock()
+
+		newtLogger.Info("Reconnect attempt %d in %s", attempt, delay.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-s.wake:
+		case <-time.After(delay):
+		}
+
+		if err := s.refreshCredentials(); err != nil {
+			newtLogger.Warn("Failed to refresh client credentials: %v", err)
+			s.mu.Lock()
+			s.state.LastError = err.Error()
+			s.mu.Unlock()
+			continue
+		}
+
+		s.syncConfig()
+		if err := s.olm.StopTunnel(); err != nil {
+			newtLogger.Warn("Failed to stop the tunnel: %v", err)
+		}
+		s.mu.Lock()
+		s.state.NextAttempt = time.Time{}
+		s.save()
+		s.mu.Unlock()
+		go s.olm.StartTunnel(s.config)
+		return true
+	}
+}
+
+// syncConfig carries changes made to the running tunnel since it started,
+// such as `pangolin select org` switching it to another organization, over
+// to the config it is restarted with.
+func (s *supervisor) syncConfig() {
+	if orgID := s.olm.GetStatus().OrgID; orgID != "" {
+		s.config.OrgID = orgID
+	}
+	if s.userToken != nil {
+		if token := s.userToken(); token != "" {
+			s.config.UserToken = token
+		}
+	}
+}
+
+// refreshCredentials replaces credentials the server rejected.
+func (s *supervisor) refreshCredentials() error {
+	s.mu.Lock()
+	rejected := s.authRejected
+	s.mu.Unlock()
+	if !rejected || s.refresh == nil {
+		return nil
+	}
+
+	id, secret, err := s.refresh()
+	if err != nil {
+		return err
+	}
+	s.config.ID, s.config.Secret = id, secret
+	s.mu.Lock()
+	s.authRejected = false
+	s.mu.Unlock()
+	return nil
+}
+
+// save writes the state file; s.mu must be held.
+func (s *supervisor) save() {
+	if err := olm.WriteReconnectState(s.statePath, s.state); err != nil {
+		newtLogger.Debug("Failed to write reconnect state: %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/fosrl/cli/internal/killswitch"
 	"github.com/fosrl/cli/internal/logger"
 	dnsOverride "github.com/fosrl/olm/dns/override"
 	"github.com/spf13/cobra"
@@ -16,8 +17,8 @@ import (
 
 // WatchdogCmd returns the hidden `pangolin watchdog` command. It is
 // spawned by the long-running client process so that DNS overrides are
-// reset if the client dies before restoring them. End users do not need
-// to invoke this directly.
+// reset if the client dies before restoring them, while its kill switch is
+// kept in place. End users do not need to invoke this directly.
 func WatchdogCmd() *cobra.Command {
 	var (
 		parentPID     int
@@ -32,8 +33,9 @@ func WatchdogCmd() *cobra.Command {
 		Hidden: true,
 		Short:  "Internal DNS override watchdog",
 		Long: `Internal command spawned by the client to monitor a running
-olm process and forcibly reset DNS if it dies. End users do
-not need to invoke this directly.`,
+olm process and forcibly reset DNS if it dies. A kill switch
+installed by the client is reinstated rather than removed. End
+users do not need to invoke this directly.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if parentPID <= 0 {
 				return errors.New("--parent-pid is required")
@@ -49,7 +51,12 @@ not need to invoke this directly.`,
 				CheckInterval:    interval,
 				FailureThreshold: threshold,
 			})
-			if err != nil && !errors.Is(err, context.Canceled) {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			// The client is gone: make sure its kill switch outlives it.
+			keepKillSwitch(interfaceName)
+			if err != nil {
 				logger.Error("Watchdog exited with error: %v", err)
 				return err
 			}
@@ -65,3 +72,28 @@ not need to invoke this directly.`,
 
 	return cmd
 }
+
+// keepKillSwitch reinstates the kill switch recorded for iface, in case the
+// client died while changing it.
+func keepKillSwitch(iface string) {
+	if killswitch.ValidateInterface(iface) != nil {
+		return
+	}
+	rules, err := killswitch.Load(killswitch.StatePath(iface))
+	if err != nil {
+		logger.Error("Failed to read the kill switch state: %v", err)
+		return
+	}
+	if rules == nil || rules.Mode == killswitch.ModeOff {
+		return
+	}
+	if rules.Interface != iface {
+		logger.Error("Ignoring the kill switch state: it is for interface %q, not %q", rules.Interface, iface)
+		return
+	}
+	if err := killswitch.Apply(*rules); err != nil {
+		logger.Error("Failed to reinstate the kill switch: %v", err)
+		return
+	}
+	logger.Warning("Kill switch kept in place for %s; run `sudo pangolin reset-dns` to remove it", iface)
+}
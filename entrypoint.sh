@@ -3,9 +3,10 @@
 set -e
 
 # Check if Pangolin environment variables are set
-if [ -n "$PANGOLIN_ENDPOINT" ] && [ -n "$CLIENT_ID" ] && [ -n "$CLIENT_SECRET" ]; then
-    # Run pangolin-cli up --attach with the provided credentials
-    exec pangolin-cli up --attach --id "$CLIENT_ID" --secret "$CLIENT_SECRET" --endpoint "$PANGOLIN_ENDPOINT" "$@"
+if [ -n "$PANGOLIN_ENDPOINT" ] && [ -n "$CLIENT_ID" ] && { [ -n "$CLIENT_SECRET" ] || [ -n "$CLIENT_SECRET_FILE" ]; }; then
+    # Run pangolin-cli up --attach; it reads the credentials from the
+    # environment so the secret never appears on its command line
+    exec pangolin-cli up --attach "$@"
 fi
 
 # If no arguments provided, run pangolin-cli with default behavior
@@ -2,9 +2,11 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/fosrl/cli/internal/logger"
 	"github.com/spf13/viper"
 )
 
@@ -15,25 +17,42 @@ type AccountStore struct {
 
 	readOnly bool
 
+	// secrets resolves and stores session tokens and OLM secrets that are
+	// kept outside of accounts.json.
+	secrets *secretResolver
+	// resolved caches secret values by reference so unchanged secrets are
+	// not rewritten on every save.
+	resolved map[string]string
+	// unresolved holds references whose backend could not be opened in this
+	// process (e.g. the keyring from a root subprocess). They are preserved
+	// as-is on save.
+	unresolved map[string]bool
+
 	ActiveUserID string             `mapstructure:"activeUserId" json:"activeUserId"`
 	Accounts     map[string]Account `mapstructure:"accounts" json:"accounts"`
 }
 
 type Account struct {
-	UserID         string          `mapstructure:"userId" json:"userId"`
-	Host           string          `mapstructure:"host" json:"host"`
-	Email          string          `mapstructure:"email" json:"email"`
-	Username       *string         `mapstructure:"username" json:"username,omitempty"`
-	Name           *string         `mapstructure:"name" json:"name,omitempty"`
-	SessionToken   string          `mapstructure:"sessionToken" json:"sessionToken"`
-	OrgID          string          `mapstructure:"orgId" json:"orgId,omitempty"`
-	OlmCredentials *OlmCredentials `mapstructure:"olmCredentials" json:"olmCredentials,omitempty"`
-	ServerInfo     *ServerInfo     `mapstructure:"serverInfo" json:"serverInfo,omitempty"`
+	UserID       string  `mapstructure:"userId" json:"userId"`
+	Host         string  `mapstructure:"host" json:"host"`
+	Email        string  `mapstructure:"email" json:"email"`
+	Username     *string `mapstructure:"username" json:"username,omitempty"`
+	Name         *string `mapstructure:"name" json:"name,omitempty"`
+	SessionToken string  `mapstructure:"sessionToken" json:"sessionToken"`
+	// SessionTokenRef points at the session token in a secret backend
+	// ("<backend>:<key>"). When set, SessionToken is empty on disk.
+	SessionTokenRef string          `mapstructure:"sessionTokenRef" json:"sessionTokenRef,omitempty"`
+	OrgID           string          `mapstructure:"orgId" json:"orgId,omitempty"`
+	OlmCredentials  *OlmCredentials `mapstructure:"olmCredentials" json:"olmCredentials,omitempty"`
+	ServerInfo      *ServerInfo     `mapstructure:"serverInfo" json:"serverInfo,omitempty"`
 }
 
 type OlmCredentials struct {
 	ID     string `mapstructure:"id" json:"id"`
 	Secret string `mapstructure:"secret" json:"secret"`
+	// SecretRef points at the secret in a secret backend ("<backend>:<key>").
+	// When set, Secret is empty on disk.
+	SecretRef string `mapstructure:"secretRef" json:"secretRef,omitempty"`
 }
 
 // ServerInfo represents server information including version, build type, and license status
@@ -57,6 +76,7 @@ func newAccountViper() (*viper.Viper, error) {
 	accountsFile := filepath.Join(dir, "accounts.json")
 	v.SetConfigFile(accountsFile)
 	v.SetConfigType("json")
+	v.SetConfigPermissions(0o600)
 
 	return v, nil
 }
@@ -69,6 +89,9 @@ func LoadAccountStore(cfg *Config) (*AccountStore, error) {
 
 	store := AccountStore{
 		v:            v,
+		secrets:      newSecretResolver(cfg),
+		resolved:     map[string]string{},
+		unresolved:   map[string]bool{},
 		ActiveUserID: "",
 		Accounts:     map[string]Account{},
 	}
@@ -84,9 +107,126 @@ func LoadAccountStore(cfg *Config) (*AccountStore, error) {
 		return nil, err
 	}
 
+	// Transparently move secrets out of a plaintext accounts.json once a
+	// secret backend is available. Otherwise references are resolved only
+	// when an account is used, so commands that need no secret never open a
+	// backend.
+	if store.hasPlaintextSecrets() {
+		if backend, err := store.secrets.writeBackend(); err == nil && backend != nil {
+			store.ResolveSecrets()
+			if err := store.Save(); err != nil {
+				logger.Warning("Failed to migrate account secrets to %s: %v", backend.Name(), err)
+			} else {
+				logger.Debug("Migrated account secrets to %s", backend.Name())
+			}
+		}
+	}
+
 	return &store, nil
 }
 
+// hasPlaintextSecrets reports whether any secret is stored inline in
+// accounts.json.
+func (s *AccountStore) hasPlaintextSecrets() bool {
+	for _, account := range s.Accounts {
+		if account.SessionToken != "" {
+			return true
+		}
+		if account.OlmCredentials != nil && account.OlmCredentials.Secret != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecrets fills in the secrets of every account, for callers that
+// need all of them rather than those of the account in use.
+func (s *AccountStore) ResolveSecrets() {
+	for userID := range s.Accounts {
+		s.resolveAccount(userID)
+	}
+}
+
+// resolveAccount fills in SessionToken and OlmCredentials.Secret of one
+// account from their references. Each reference is looked up at most once.
+func (s *AccountStore) resolveAccount(userID string) {
+	account, ok := s.Accounts[userID]
+	if !ok || s.secrets == nil {
+		return
+	}
+	if account.SessionToken == "" && s.pending(account.SessionTokenRef) {
+		account.SessionToken = s.resolveRef(account.SessionTokenRef)
+	}
+	if account.OlmCredentials != nil {
+		creds := *account.OlmCredentials
+		if creds.Secret == "" && s.pending(creds.SecretRef) {
+			creds.Secret = s.resolveRef(creds.SecretRef)
+		}
+		account.OlmCredentials = &creds
+	}
+	s.Accounts[userID] = account
+}
+
+// pending reports whether ref has not been looked up yet.
+func (s *AccountStore) pending(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	_, resolved := s.resolved[ref]
+	return !resolved && !s.unresolved[ref]
+}
+
+func (s *AccountStore) resolveRef(ref string) string {
+	value, err := s.secrets.resolve(ref)
+	if err != nil {
+		logger.Debug("Failed to resolve secret %s: %v", ref, err)
+		s.unresolved[ref] = true
+		return ""
+	}
+	s.resolved[ref] = value
+	return value
+}
+
+// persistSecret writes value to backend (or keeps it inline when backend is
+// nil) and returns the inline value and reference to store on disk.
+func (s *AccountStore) persistSecret(backend SecretStore, key, value, ref string) (string, string, error) {
+	if value == "" {
+		// A reference that was never looked up, or could not be, still
+		// holds the secret.
+		if ref != "" && (s.unresolved[ref] || s.pending(ref)) {
+			return "", ref, nil
+		}
+		if ref != "" {
+			s.secrets.forget(ref)
+			delete(s.resolved, ref)
+		}
+		return "", "", nil
+	}
+
+	if backend == nil {
+		if ref != "" {
+			s.secrets.forget(ref)
+			delete(s.resolved, ref)
+		}
+		return value, "", nil
+	}
+
+	newRef := secretRef{backend: backend.Name(), key: key}.String()
+	if cached, ok := s.resolved[newRef]; ok && cached == value && ref == newRef {
+		return "", newRef, nil
+	}
+	if err := backend.Set(key, value); err != nil {
+		return "", "", fmt.Errorf("store secret in %s: %w", backend.Name(), err)
+	}
+	if ref != "" && ref != newRef {
+		s.secrets.forget(ref)
+		delete(s.resolved, ref)
+	}
+	s.resolved[newRef] = value
+	delete(s.unresolved, newRef)
+	return "", newRef, nil
+}
+
 // NewReadOnlyAccountStore builds an in-memory account store from desktop app session data.
 func NewReadOnlyAccountStore(activeUserID string, accounts map[string]Account) *AccountStore {
 	if accounts == nil {
@@ -109,6 +249,7 @@ func (s *AccountStore) ActiveAccount() (*Account, error) {
 		return nil, errors.New("not logged in")
 	}
 
+	s.resolveAccount(s.ActiveUserID)
 	activeAccount, exists := s.Accounts[s.ActiveUserID]
 	if !exists {
 		return nil, errors.New("active account missing")
@@ -130,6 +271,9 @@ func (s *AccountStore) Deactivate(userID string) error {
 	if s.readOnly {
 		return errors.New("account store is read-only")
 	}
+	// Resolve the token first so that logging out removes it from the
+	// secret backend.
+	s.resolveAccount(userID)
 	account, exists := s.Accounts[userID]
 	if !exists {
 		return errors.New("account does not exist")
@@ -153,6 +297,7 @@ func (s *AccountStore) Deactivate(userID string) error {
 func (s *AccountStore) AvailableAccounts() []Account {
 	available := []Account{}
 
+	s.ResolveSecrets()
 	for _, account := range s.Accounts {
 		if account.SessionToken != "" {
 			available = append(available, account)
@@ -188,14 +333,68 @@ func (s *AccountStore) Save() error {
 	if s.v == nil {
 		return errors.New("account store has no backing config file")
 	}
+
+	// A backend that cannot be opened or written, such as a locked vault
+	// with no terminal to prompt on, must not lose secrets that were just
+	// rotated: they stay in memory, the previous references stay on disk,
+	// and the next Save tries again. Secrets that were never moved out of
+	// accounts.json stay there.
+	backend, deferErr := s.secrets.writeBackend()
+	persist := func(key, value, ref string) (string, string) {
+		if deferErr == nil {
+			onDisk, newRef, err := s.persistSecret(backend, key, value, ref)
+			if err == nil {
+				return onDisk, newRef
+			}
+			deferErr = err
+		}
+		if ref == "" || value == "" {
+			return value, ""
+		}
+		return "", ref
+	}
+
+	// Secrets stay in memory on s.Accounts; only references are written
+	// to disk when a backend is in use.
+	persisted := make(map[string]Account, len(s.Accounts))
+	for userID, account := range s.Accounts {
+		onDisk := account
+
+		onDisk.SessionToken, account.SessionTokenRef = persist(userID+"/sessionToken", account.SessionToken, account.SessionTokenRef)
+		onDisk.SessionTokenRef = account.SessionTokenRef
+
+		if account.OlmCredentials != nil {
+			creds := *account.OlmCredentials
+			diskCreds := creds
+			diskCreds.Secret, creds.SecretRef = persist(userID+"/olmSecret", creds.Secret, creds.SecretRef)
+			diskCreds.SecretRef = creds.SecretRef
+			account.OlmCredentials = &creds
+			onDisk.OlmCredentials = &diskCreds
+		}
+
+		s.Accounts[userID] = account
+		persisted[userID] = onDisk
+	}
+	if deferErr != nil {
+		logger.Warning("Secrets could not be saved (%v); new values are kept in memory only until the secret backend is available", deferErr)
+	}
+
 	// HACK: If there's a better way to write the config all at once
 	// without having to specify each toplevel struct key, that
 	// would be preferable.
 	// However, this is fine for now.
 	s.v.Set("activeUserId", s.ActiveUserID)
-	s.v.Set("accounts", s.Accounts)
+	s.v.Set("accounts", persisted)
+
+	if err := s.v.WriteConfig(); err != nil {
+		return err
+	}
 
-	return s.v.WriteConfig()
+	// Tighten permissions on files created before secrets were moved out.
+	if path := s.v.ConfigFileUsed(); path != "" {
+		_ = os.Chmod(path, 0o600)
+	}
+	return nil
 }
 
 // UpdateAccountUserInfo updates the username and name for a specific account
@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/fosrl/cli/internal/killswitch"
 	"github.com/fosrl/cli/internal/logger"
 	"github.com/spf13/viper"
 )
@@ -23,11 +24,13 @@ type Config struct {
 	DisableUpdateCheck   bool                 `mapstructure:"disable_update_check" json:"disable_update_check"`
 	DisableCompanionMode bool                 `mapstructure:"disable_companion_mode" json:"disable_companion_mode"`
 	CompanionAppDataDirs CompanionAppDataDirs `mapstructure:"companion_app_data_dirs" json:"companion_app_data_dirs"`
+	SecretBackend        string               `mapstructure:"secret_backend" json:"secret_backend"`
 	Up                   UpConfig             `mapstructure:"up" json:"up,omitempty"`
 }
 
 // UpConfig holds persistent defaults for pangolin up DNS-related flags.
-// Pointer bools distinguish unset from explicitly false.
+// Pointer bools distinguish unset from explicitly false. Named profiles under
+// up.profiles are read with UpProfile.
 type UpConfig struct {
 	TunnelDNS   *bool    `mapstructure:"tunnel_dns" json:"tunnel_dns,omitempty"`
 	UpstreamDNS []string `mapstructure:"upstream_dns" json:"upstream_dns,omitempty"`
@@ -47,6 +50,10 @@ type UpConfig struct {
 	// --prefer-local-routes flag when the flag isn't passed explicitly.
 	// Defaults to false.
 	PreferLocalRoutes *bool `mapstructure:"prefer_local_routes" json:"prefer_local_routes,omitempty"`
+
+	// KillSwitch is the default for `pangolin up`'s --kill-switch flag: off,
+	// protected or all. Empty leaves the kill switch off.
+	KillSwitch string `mapstructure:"kill_switch" json:"kill_switch,omitempty"`
 }
 
 // CompanionAppDataDirs holds per-platform overrides for the desktop app data directory.
@@ -60,11 +67,19 @@ var ConfigOptions = []string{
 	"log_level",
 	"disable_update_check",
 	"disable_companion_mode",
+	"secret_backend",
 	"up.tunnel_dns",
 	"up.upstream_dns",
 	"up.override_dns",
 	"up.match_domains_dns",
 	"up.prefer_local_routes",
+	"up.kill_switch",
+	"hooks.pre_up",
+	"hooks.post_up",
+	"hooks.pre_down",
+	"hooks.post_down",
+	"hooks.on_peer_change",
+	"hooks.timeout",
 }
 
 // SupportedConfigKeys returns the settable config keys.
@@ -115,6 +130,7 @@ func newConfigViper() (*viper.Viper, error) {
 	v.SetDefault("disable_update_check", false)
 	v.SetDefault("disable_companion_mode", false)
 	v.SetDefault("companion_app_data_dirs", map[string]string{})
+	v.SetDefault("secret_backend", string(SecretBackendAuto))
 	v.SetDefault("up.match_domains_dns", []string{})
 
 	return v, nil
@@ -222,6 +238,13 @@ func (c *Config) SetKey(key, value string) error {
 		}
 		c.DisableCompanionMode = b
 		c.v.Set(key, b)
+	case "secret_backend":
+		backend, err := ParseSecretBackend(value)
+		if err != nil {
+			return err
+		}
+		c.SecretBackend = string(backend)
+		c.v.Set(key, string(backend))
 	case "up.tunnel_dns":
 		b, err := parseBool(value)
 		if err != nil {
@@ -251,6 +274,16 @@ func (c *Config) SetKey(key, value string) error {
 		}
 		c.Up.PreferLocalRoutes = &b
 		c.v.Set(key, b)
+	case "up.kill_switch":
+		mode, err := killswitch.ParseMode(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		c.Up.KillSwitch = string(mode)
+		c.v.Set(key, string(mode))
+	case "hooks.pre_up", "hooks.post_up", "hooks.pre_down", "hooks.post_down", "hooks.on_peer_change", "hooks.timeout":
+		// Hooks live in their own root-owned file, written right away.
+		return setHook(key, value)
 	default:
 		return fmt.Errorf("unknown config key %q; supported keys: %s", key, strings.Join(SupportedConfigKeys(), ", "))
 	}
@@ -268,6 +301,8 @@ func (c *Config) GetKey(key string) (string, error) {
 		return fmt.Sprintf("%t", c.DisableUpdateCheck), nil
 	case "disable_companion_mode":
 		return fmt.Sprintf("%t", c.DisableCompanionMode), nil
+	case "secret_backend":
+		return c.SecretBackend, nil
 	case "up.tunnel_dns":
 		if !c.IsSet(key) {
 			return "", errConfigKeyUnset(key)
@@ -293,6 +328,20 @@ func (c *Config) GetKey(key string) (string, error) {
 			return "", errConfigKeyUnset(key)
 		}
 		return fmt.Sprintf("%t", c.GetBool(key)), nil
+	case "up.kill_switch":
+		if !c.IsSet(key) {
+			return "", errConfigKeyUnset(key)
+		}
+		return c.GetString(key), nil
+	case "hooks.pre_up", "hooks.post_up", "hooks.pre_down", "hooks.post_down", "hooks.on_peer_change", "hooks.timeout":
+		value, err := getHook(key)
+		if err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "", errConfigKeyUnset(key)
+		}
+		return value, nil
 	default:
 		return "", fmt.Errorf("unknown config key %q; supported keys: %s", key, strings.Join(SupportedConfigKeys(), ", "))
 	}
@@ -331,6 +380,7 @@ func (c *Config) Save() error {
 	c.v.Set("disable_update_check", c.DisableUpdateCheck)
 	c.v.Set("disable_companion_mode", c.DisableCompanionMode)
 	c.v.Set("companion_app_data_dirs", c.CompanionAppDataDirs)
+	c.v.Set("secret_backend", c.SecretBackend)
 
 	// Only persist up keys that were explicitly set so we do not write
 	// zero-value bools that would later look like intentional overrides.
@@ -349,6 +399,9 @@ func (c *Config) Save() error {
 	if c.Up.PreferLocalRoutes != nil {
 		c.v.Set("up.prefer_local_routes", *c.Up.PreferLocalRoutes)
 	}
+	if c.Up.KillSwitch != "" {
+		c.v.Set("up.kill_switch", c.Up.KillSwitch)
+	}
 
 	dir, err := GetPangolinConfigDir()
 	if err != nil {
@@ -403,6 +456,27 @@ func userHomeDir() (string, error) {
 	return os.UserHomeDir()
 }
 
+// ClientLogFile returns the log file of the named client instance: the
+// configured log file for the default instance, and a file next to it for
+// the others.
+func (c *Config) ClientLogFile(instance string) string {
+	if instance == "" || c.LogFile == "" {
+		return c.LogFile
+	}
+	ext := filepath.Ext(c.LogFile)
+	return strings.TrimSuffix(c.LogFile, ext) + "-" + instance + ext
+}
+
+// EventJournalDir returns the directory holding the client event journals,
+// which is the directory of the client log.
+func (c *Config) EventJournalDir() string {
+	logFile := c.LogFile
+	if logFile == "" {
+		logFile = defaultLogPath()
+	}
+	return filepath.Dir(logFile)
+}
+
 // defaultLogPath returns the default log file path for client logs
 func defaultLogPath() string {
 	pangolinDir, err := GetPangolinConfigDir()
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout bounds a hook command when hooks.timeout is not set.
+const DefaultHookTimeout = 30 * time.Second
+
+// HooksConfig holds shell commands the client runs at points of its
+// lifecycle. They run with the client's privileges, usually as root, so they
+// are kept in HooksFilePath rather than in the user's config file.
+type HooksConfig struct {
+	PreUp        string `mapstructure:"pre_up" json:"pre_up,omitempty"`
+	PostUp       string `mapstructure:"post_up" json:"post_up,omitempty"`
+	PreDown      string `mapstructure:"pre_down" json:"pre_down,omitempty"`
+	PostDown     string `mapstructure:"post_down" json:"post_down,omitempty"`
+	OnPeerChange string `mapstructure:"on_peer_change" json:"on_peer_change,omitempty"`
+	Timeout      string `mapstructure:"timeout" json:"timeout,omitempty"`
+}
+
+// Commands returns the configured hook commands keyed by hook name, such
+// as "post_up".
+func (h HooksConfig) Commands() map[string]string {
+	out := map[string]string{}
+	for name, command := range map[string]string{
+		"pre_up":         h.PreUp,
+		"post_up":        h.PostUp,
+		"pre_down":       h.PreDown,
+		"post_down":      h.PostDown,
+		"on_peer_change": h.OnPeerChange,
+	} {
+		if command != "" {
+			out[name] = command
+		}
+	}
+	return out
+}
+
+// TimeoutDuration returns how long a hook may run.
+func (h HooksConfig) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHookTimeout
+}
+
+// LoadHooks reads the hooks file. A missing file has no hooks. On Unix the
+// file and every directory above it must be owned by root and writable by
+// no one else, since anyone who can change it can run commands as root.
+func LoadHooks() (HooksConfig, error) {
+	var h HooksConfig
+	path := HooksFilePath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err := checkHooksFileOwner(path); err != nil {
+		return h, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h, err
+	}
+	if err := json.Unmarshal(data, &h); err != nil {
+		return h, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return h, nil
+}
+
+// SaveHooks writes h to the hooks file, which only root may do.
+func SaveHooks(h HooksConfig) error {
+	path := HooksFilePath()
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("hooks are stored in %s, which only root can change; run the command with sudo", path)
+	}
+	return err
+}
+
+// setHook changes one hooks.* key in the hooks file.
+func setHook(key, value string) error {
+	name := strings.TrimPrefix(key, "hooks.")
+	if name == "timeout" {
+		if err := validateHookTimeout(value); err != nil {
+			return err
+		}
+	}
+	h, err := LoadHooks()
+	if err != nil {
+		return err
+	}
+	h.set(name, value)
+	return SaveHooks(h)
+}
+
+// getHook returns the value of one hooks.* key from the hooks file.
+func getHook(key string) (string, error) {
+	h, err := LoadHooks()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(key, "hooks.")
+	if name == "timeout" {
+		return h.Timeout, nil
+	}
+	return h.Commands()[name], nil
+}
+
+func (h *HooksConfig) set(name, command string) {
+	switch name {
+	case "pre_up":
+		h.PreUp = command
+	case "post_up":
+		h.PostUp = command
+	case "pre_down":
+		h.PreDown = command
+	case "post_down":
+		h.PostDown = command
+	case "on_peer_change":
+		h.OnPeerChange = command
+	case "timeout":
+		h.Timeout = command
+	}
+}
+
+func validateHookTimeout(value string) error {
+	if value == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return fmt.Errorf("invalid hooks.timeout %q: use a duration such as 30s", value)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// HooksFilePath returns the file holding the client's hook commands.
+func HooksFilePath() string {
+	return "/etc/pangolin/hooks.json"
+}
+
+// checkHooksFileOwner refuses path unless it and each directory above it
+// are owned by root and not writable by group or others.
+func checkHooksFileOwner(path string) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	for p := resolved; ; p = filepath.Dir(p) {
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); !ok || st.Uid != 0 {
+			return fmt.Errorf("%s is not owned by root", p)
+		}
+		if info.Mode().Perm()&0o022 != 0 {
+			return fmt.Errorf("%s is writable by other users", p)
+		}
+		if p == filepath.Dir(p) {
+			return nil
+		}
+	}
+}
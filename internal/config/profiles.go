@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type upProfileKind int
+
+const (
+	upProfileString upProfileKind = iota
+	upProfileBool
+	upProfileInt
+	upProfileDuration
+	upProfileList
+)
+
+// UpProfileOption is a pangolin up setting that a profile can store.
+type UpProfileOption struct {
+	// Key is the setting's name within the profile, such as "mtu".
+	Key string
+	// Flag is the pangolin up flag the setting supplies.
+	Flag string
+	kind upProfileKind
+}
+
+// UpProfileOptions lists the settings a profile may contain. The client
+// secret can only be stored as a file reference, so profiles never hold it
+// in plain text.
+var UpProfileOptions = []UpProfileOption{
+	{Key: "id", Flag: "id"},
+	{Key: "secret_file", Flag: "secret-file"},
+	{Key: "endpoint", Flag: "endpoint"},
+	{Key: "org", Flag: "org"},
+	{Key: "mtu", Flag: "mtu", kind: upProfileInt},
+	{Key: "netstack_dns", Flag: "netstack-dns"},
+	{Key: "interface_name", Flag: "interface-name"},
+	{Key: "log_level", Flag: "log-level"},
+	{Key: "http_addr", Flag: "http-addr"},
+	{Key: "ping_interval", Flag: "ping-interval", kind: upProfileDuration},
+	{Key: "ping_timeout", Flag: "ping-timeout", kind: upProfileDuration},
+	{Key: "holepunch", Flag: "holepunch", kind: upProfileBool},
+	{Key: "tls_client_cert", Flag: "tls-client-cert"},
+	{Key: "override_dns", Flag: "override-dns", kind: upProfileBool},
+	{Key: "tunnel_dns", Flag: "tunnel-dns", kind: upProfileBool},
+	{Key: "upstream_dns", Flag: "upstream-dns", kind: upProfileList},
+	{Key: "match_domains_dns", Flag: "match-domains", kind: upProfileList},
+	{Key: "prefer_local_routes", Flag: "prefer-local-routes", kind: upProfileBool},
+	{Key: "kill_switch", Flag: "kill-switch"},
+	{Key: "attach", Flag: "attach", kind: upProfileBool},
+	{Key: "silent", Flag: "silent", kind: upProfileBool},
+}
+
+// Profile names are lower case because config keys are case-insensitive.
+var upProfileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateUpProfileName reports whether name can be used for a profile.
+func ValidateUpProfileName(name string) error {
+	if !upProfileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lower-case letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// UpProfileOptionKeys returns the keys a profile may contain.
+func UpProfileOptionKeys() []string {
+	keys := make([]string, len(UpProfileOptions))
+	for i, opt := range UpProfileOptions {
+		keys[i] = opt.Key
+	}
+	return keys
+}
+
+func lookupUpProfileOption(key string) (UpProfileOption, bool) {
+	for _, opt := range UpProfileOptions {
+		if opt.Key == key {
+			return opt, true
+		}
+	}
+	return UpProfileOption{}, false
+}
+
+// UpProfileNames returns the names of the configured profiles, sorted.
+func (c *Config) UpProfileNames() []string {
+	profiles := c.v.GetStringMap("up.profiles")
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UpProfile returns the settings of the named profile as flag values keyed
+// by setting name, with lists joined by commas.
+func (c *Config) UpProfile(name string) (map[string]string, bool) {
+	key := "up.profiles." + name
+	if !c.v.IsSet(key) {
+		return nil, false
+	}
+
+	out := map[string]string{}
+	for k, v := range c.v.GetStringMap(key) {
+		switch v := v.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[k] = strings.Join(items, ",")
+		case []string:
+			out[k] = strings.Join(v, ",")
+		default:
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out, true
+}
+
+// SetUpProfile validates values and stores them as the named profile,
+// replacing any profile of that name.
+func (c *Config) SetUpProfile(name string, values map[string]string) error {
+	if err := ValidateUpProfileName(name); err != nil {
+		return err
+	}
+
+	profile := make(map[string]any, len(values))
+	for k, v := range values {
+		opt, ok := lookupUpProfileOption(k)
+		if !ok {
+			return fmt.Errorf("unknown profile key %q; supported keys: %s", k, strings.Join(UpProfileOptionKeys(), ", "))
+		}
+		switch opt.kind {
+		case upProfileBool:
+			b, err := parseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			profile[k] = b
+		case upProfileInt:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return fmt.Errorf("%s: invalid number %q", k, v)
+			}
+			profile[k] = n
+		case upProfileDuration:
+			if _, err := time.ParseDuration(strings.TrimSpace(v)); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			profile[k] = strings.TrimSpace(v)
+		case upProfileList:
+			profile[k] = splitCommaList(v)
+		default:
+			profile[k] = v
+		}
+	}
+
+	// Replace the whole map: setting only this profile's key would leave
+	// settings it no longer has visible from the config file.
+	profiles := c.v.GetStringMap("up.profiles")
+	profiles[name] = profile
+	c.v.Set("up.profiles", profiles)
+	return nil
+}
+
+// DeleteUpProfile removes the named profile and reports whether it existed.
+func (c *Config) DeleteUpProfile(name string) bool {
+	profiles := c.v.GetStringMap("up.profiles")
+	if _, ok := profiles[name]; !ok {
+		return false
+	}
+	delete(profiles, name)
+	c.v.Set("up.profiles", profiles)
+	return true
+}
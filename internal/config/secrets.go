@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fosrl/cli/internal/logger"
+)
+
+// SecretBackend names a place where account secrets (session tokens and
+// OLM secrets) are stored instead of plaintext accounts.json.
+type SecretBackend string
+
+const (
+	// SecretBackendAuto picks the passphrase vault when it exists or
+	// PANGOLIN_CLI_VAULT_KEY is set, and falls back to plaintext. The OS
+	// keyring is never picked automatically: root cannot read the user's
+	// keyring under sudo, so the client and service install would lose the
+	// secrets.
+	SecretBackendAuto SecretBackend = "auto"
+	// SecretBackendPlaintext keeps secrets inline in accounts.json.
+	SecretBackendPlaintext SecretBackend = "plaintext"
+	// SecretBackendKeyring stores secrets in the OS keyring
+	// (the Secret Service over D-Bus on Linux).
+	SecretBackendKeyring SecretBackend = "keyring"
+	// SecretBackendVault stores secrets in an encrypted file unlocked by a
+	// passphrase or PANGOLIN_CLI_VAULT_KEY.
+	SecretBackendVault SecretBackend = "vault"
+)
+
+// EnvVaultKey supplies the vault passphrase non-interactively.
+const EnvVaultKey = "PANGOLIN_CLI_VAULT_KEY"
+
+var errSecretBackendUnavailable = errors.New("secret backend is not available")
+
+// SecretStore persists named secrets outside of accounts.json.
+type SecretStore interface {
+	Name() SecretBackend
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// ParseSecretBackend validates a secret backend name from config.
+func ParseSecretBackend(value string) (SecretBackend, error) {
+	switch b := SecretBackend(strings.ToLower(strings.TrimSpace(value))); b {
+	case "":
+		return SecretBackendAuto, nil
+	case SecretBackendAuto, SecretBackendPlaintext, SecretBackendKeyring, SecretBackendVault:
+		return b, nil
+	default:
+		return "", fmt.Errorf("invalid secret_backend %q: must be auto, plaintext, keyring, or vault", value)
+	}
+}
+
+// secretRef is a pointer to a secret held by a SecretStore, persisted in
+// accounts.json as "<backend>:<key>".
+type secretRef struct {
+	backend SecretBackend
+	key     string
+}
+
+func parseSecretRef(ref string) (secretRef, bool) {
+	backend, key, ok := strings.Cut(ref, ":")
+	if !ok || backend == "" || key == "" {
+		return secretRef{}, false
+	}
+	return secretRef{backend: SecretBackend(backend), key: key}, true
+}
+
+func (r secretRef) String() string {
+	return string(r.backend) + ":" + r.key
+}
+
+// secretResolver lazily opens secret backends so that commands that never
+// touch a secret do not prompt for a vault passphrase or dial D-Bus.
+type secretResolver struct {
+	configured SecretBackend
+	opened     map[SecretBackend]SecretStore
+	failed     map[SecretBackend]error
+}
+
+func newSecretResolver(cfg *Config) *secretResolver {
+	configured := SecretBackendAuto
+	if cfg != nil {
+		if b, err := ParseSecretBackend(cfg.SecretBackend); err == nil {
+			configured = b
+		}
+	}
+	return &secretResolver{
+		configured: configured,
+		opened:     map[SecretBackend]SecretStore{},
+		failed:     map[SecretBackend]error{},
+	}
+}
+
+func (r *secretResolver) open(backend SecretBackend) (SecretStore, error) {
+	if store, ok := r.opened[backend]; ok {
+		return store, nil
+	}
+	if err, ok := r.failed[backend]; ok {
+		return nil, err
+	}
+
+	var store SecretStore
+	var err error
+	switch backend {
+	case SecretBackendKeyring:
+		store, err = openKeyringSecretStore()
+	case SecretBackendVault:
+		store, err = openVaultSecretStore()
+	default:
+		err = fmt.Errorf("unknown secret backend %q", backend)
+	}
+	if err != nil {
+		r.failed[backend] = err
+		return nil, err
+	}
+	r.opened[backend] = store
+	return store, nil
+}
+
+// writeBackend returns the store new secrets should be written to, or nil
+// when secrets should stay in plaintext.
+func (r *secretResolver) writeBackend() (SecretStore, error) {
+	switch r.configured {
+	case SecretBackendPlaintext:
+		return nil, nil
+	case SecretBackendKeyring, SecretBackendVault:
+		return r.open(r.configured)
+	}
+
+	if os.Getenv(EnvVaultKey) != "" || vaultExists() {
+		return r.open(SecretBackendVault)
+	}
+	return nil, nil
+}
+
+// resolve returns the secret behind ref.
+func (r *secretResolver) resolve(ref string) (string, error) {
+	parsed, ok := parseSecretRef(ref)
+	if !ok {
+		return "", fmt.Errorf("malformed secret reference %q", ref)
+	}
+	store, err := r.open(parsed.backend)
+	if err != nil {
+		return "", err
+	}
+	return store.Get(parsed.key)
+}
+
+// forget removes the secret behind ref, ignoring backends that cannot be opened.
+func (r *secretResolver) forget(ref string) {
+	parsed, ok := parseSecretRef(ref)
+	if !ok {
+		return
+	}
+	store, err := r.open(parsed.backend)
+	if err != nil {
+		return
+	}
+	if err := store.Delete(parsed.key); err != nil {
+		logger.Debug("Failed to delete secret %s: %v", ref, err)
+	}
+}
@@ -0,0 +1,213 @@
+//go:build linux
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Secret Service API (https://specifications.freedesktop.org/secret-service/)
+// names used by the keyring backend.
+const (
+	secretServiceName       = "org.freedesktop.secrets"
+	secretServicePath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceInterface  = "org.freedesktop.Secret.Service"
+	secretCollectionIface   = "org.freedesktop.Secret.Collection"
+	secretItemIface         = "org.freedesktop.Secret.Item"
+	secretPromptIface       = "org.freedesktop.Secret.Prompt"
+	secretDefaultCollection = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+
+	// keyringAttrService tags every item this CLI creates so lookups do not
+	// collide with other applications.
+	keyringAttrService = "pangolin-cli"
+
+	// keyringPromptTimeout bounds the wait for an unlock prompt, which in a
+	// headless session nobody will ever answer.
+	keyringPromptTimeout = 2 * time.Minute
+)
+
+// dbusSecret mirrors the Secret Service (oayays) secret struct.
+type dbusSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+type keyringSecretStore struct {
+	conn    *dbus.Conn
+	session dbus.ObjectPath
+}
+
+func openKeyringSecretStore() (SecretStore, error) {
+	conn, err := dbus.SessionBusPrivateNoAutoStartup()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSecretBackendUnavailable, err)
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", errSecretBackendUnavailable, err)
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", errSecretBackendUnavailable, err)
+	}
+
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	err = conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: open secret service session: %v", errSecretBackendUnavailable, err)
+	}
+
+	return &keyringSecretStore{conn: conn, session: session}, nil
+}
+
+func (s *keyringSecretStore) Name() SecretBackend {
+	return SecretBackendKeyring
+}
+
+func keyringAttributes(key string) map[string]string {
+	return map[string]string{
+		"service": keyringAttrService,
+		"key":     key,
+	}
+}
+
+// findItem returns the item path for key, unlocking it when needed.
+func (s *keyringSecretStore) findItem(key string) (dbus.ObjectPath, error) {
+	var unlocked, locked []dbus.ObjectPath
+	err := s.conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".SearchItems", 0, keyringAttributes(key)).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return "", fmt.Errorf("search keyring: %w", err)
+	}
+	if len(unlocked) > 0 {
+		return unlocked[0], nil
+	}
+	if len(locked) == 0 {
+		return "", nil
+	}
+
+	var prompt dbus.ObjectPath
+	err = s.conn.Object(secretServiceName, secretServicePath).
+		Call(secretServiceInterface+".Unlock", 0, locked[:1]).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return "", fmt.Errorf("unlock keyring: %w", err)
+	}
+	if err := s.runPrompt(prompt); err != nil {
+		return "", err
+	}
+	return locked[0], nil
+}
+
+// runPrompt shows a Secret Service prompt (e.g. the keyring unlock dialog)
+// and waits for the user to complete it, dismissing it after
+// keyringPromptTimeout.
+func (s *keyringSecretStore) runPrompt(prompt dbus.ObjectPath) error {
+	if prompt == "" || prompt == "/" {
+		return nil
+	}
+
+	if err := s.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptIface),
+	); err != nil {
+		return fmt.Errorf("watch keyring prompt: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretServiceName, prompt).Call(secretPromptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("show keyring prompt: %w", err)
+	}
+
+	timeout := time.NewTimer(keyringPromptTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case sig, ok := <-signals:
+			if !ok {
+				return errors.New("keyring prompt closed unexpectedly")
+			}
+			if sig.Path != prompt || sig.Name != secretPromptIface+".Completed" {
+				continue
+			}
+			if len(sig.Body) > 0 {
+				if dismissed, ok := sig.Body[0].(bool); ok && dismissed {
+					return errors.New("keyring unlock was dismissed")
+				}
+			}
+			return nil
+		case <-timeout.C:
+			_ = s.conn.Object(secretServiceName, prompt).Call(secretPromptIface+".Dismiss", 0).Err
+			return fmt.Errorf("keyring prompt was not answered within %s", keyringPromptTimeout)
+		}
+	}
+}
+
+func (s *keyringSecretStore) Get(key string) (string, error) {
+	item, err := s.findItem(key)
+	if err != nil {
+		return "", err
+	}
+	if item == "" {
+		return "", fmt.Errorf("secret %q not found in keyring", key)
+	}
+
+	var secret dbusSecret
+	err = s.conn.Object(secretServiceName, item).
+		Call(secretItemIface+".GetSecret", 0, s.session).
+		Store(&secret)
+	if err != nil {
+		return "", fmt.Errorf("read keyring item: %w", err)
+	}
+	return string(secret.Value), nil
+}
+
+func (s *keyringSecretStore) Set(key, value string) error {
+	properties := map[string]dbus.Variant{
+		secretItemIface + ".Label":      dbus.MakeVariant("Pangolin CLI: " + key),
+		secretItemIface + ".Attributes": dbus.MakeVariant(keyringAttributes(key)),
+	}
+	secret := dbusSecret{
+		Session:     s.session,
+		Value:       []byte(value),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+	err := s.conn.Object(secretServiceName, secretDefaultCollection).
+		Call(secretCollectionIface+".CreateItem", 0, properties, secret, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("write keyring item: %w", err)
+	}
+	return s.runPrompt(prompt)
+}
+
+func (s *keyringSecretStore) Delete(key string) error {
+	item, err := s.findItem(key)
+	if err != nil || item == "" {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	err = s.conn.Object(secretServiceName, item).
+		Call(secretItemIface+".Delete", 0).
+		Store(&prompt)
+	if err != nil {
+		return fmt.Errorf("delete keyring item: %w", err)
+	}
+	return s.runPrompt(prompt)
+}
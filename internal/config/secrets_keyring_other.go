@@ -0,0 +1,9 @@
+//go:build !linux
+
+package config
+
+// The OS keyring backend is only implemented for the Secret Service on
+// Linux. Other platforms use the vault or plaintext backends.
+func openKeyringSecretStore() (SecretStore, error) {
+	return nil, errSecretBackendUnavailable
+}
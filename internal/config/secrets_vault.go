@@ -0,0 +1,242 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	vaultFileName = "secrets.vault"
+	vaultVersion  = 1
+
+	// scrypt parameters recommended for interactive logins.
+	vaultScryptN = 1 << 15
+	vaultScryptR = 8
+	vaultScryptP = 1
+)
+
+// vaultFile is the on-disk format of the passphrase vault. The plaintext is
+// a JSON object of secret key to value, sealed with XChaCha20-Poly1305 under
+// a key derived from the passphrase with scrypt.
+type vaultFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type vaultSecretStore struct {
+	path    string
+	salt    []byte
+	n, r, p int
+	key     []byte
+	secrets map[string]string
+}
+
+func vaultFilePath() (string, error) {
+	dir, err := GetPangolinConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, vaultFileName), nil
+}
+
+func vaultExists() bool {
+	path, err := vaultFilePath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// openVaultSecretStore unlocks the vault, creating it on first use.
+func openVaultSecretStore() (SecretStore, error) {
+	path, err := vaultFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read vault: %w", err)
+		}
+
+		passphrase, err := vaultPassphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		key, err := scrypt.Key(passphrase, salt, vaultScryptN, vaultScryptR, vaultScryptP, chacha20poly1305.KeySize)
+		if err != nil {
+			return nil, fmt.Errorf("derive vault key: %w", err)
+		}
+		return &vaultSecretStore{
+			path:    path,
+			salt:    salt,
+			n:       vaultScryptN,
+			r:       vaultScryptR,
+			p:       vaultScryptP,
+			key:     key,
+			secrets: map[string]string{},
+		}, nil
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse vault: %w", err)
+	}
+	if file.Version != vaultVersion || file.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported vault format (version %d, kdf %q)", file.Version, file.KDF)
+	}
+
+	passphrase, err := vaultPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key(passphrase, file.Salt, file.N, file.R, file.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("derive vault key: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("unable to unlock vault: wrong passphrase or corrupted file")
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("parse vault contents: %w", err)
+	}
+
+	return &vaultSecretStore{
+		path:    path,
+		salt:    file.Salt,
+		n:       file.N,
+		r:       file.R,
+		p:       file.P,
+		key:     key,
+		secrets: secrets,
+	}, nil
+}
+
+// vaultPassphrase reads the passphrase from PANGOLIN_CLI_VAULT_KEY or, when
+// attached to a terminal, prompts for it.
+func vaultPassphrase(confirm bool) ([]byte, error) {
+	if key := os.Getenv(EnvVaultKey); key != "" {
+		return []byte(key), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return nil, fmt.Errorf("vault is locked; set %s or run interactively", EnvVaultKey)
+	}
+
+	fmt.Fprint(os.Stderr, "Vault passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("vault passphrase must not be empty")
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Confirm vault passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase: %w", err)
+		}
+		if string(again) != string(passphrase) {
+			return nil, errors.New("vault passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+func (s *vaultSecretStore) Name() SecretBackend {
+	return SecretBackendVault
+}
+
+func (s *vaultSecretStore) Get(key string) (string, error) {
+	value, ok := s.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in vault", key)
+	}
+	return value, nil
+}
+
+func (s *vaultSecretStore) Set(key, value string) error {
+	s.secrets[key] = value
+	return s.save()
+}
+
+func (s *vaultSecretStore) Delete(key string) error {
+	if _, ok := s.secrets[key]; !ok {
+		return nil
+	}
+	delete(s.secrets, key)
+	return s.save()
+}
+
+func (s *vaultSecretStore) save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	aead, err := chacha20poly1305.NewX(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(vaultFile{
+		Version:    vaultVersion,
+		KDF:        "scrypt",
+		N:          s.n,
+		R:          s.r,
+		P:          s.p,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write vault: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,448 @@
+// Package doctor runs diagnostics on the CLI's configuration, the server
+// connection and the local client, and suggests fixes for what fails.
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/companion"
+	"github.com/fosrl/cli/internal/config"
+	"github.com/fosrl/cli/internal/olm"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// Result is the outcome of one check, with a suggested fix when it did not
+// pass.
+type Result struct {
+	Check  string `json:"check"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Env is what the checks run against. Accounts and API are nil when the
+// accounts file could not be loaded; AccountsErr then says why.
+type Env struct {
+	Config      *config.Config
+	Accounts    *config.AccountStore
+	AccountsErr error
+	API         *api.Client
+}
+
+// LoadEnv loads the accounts and API client the checks need. It does not
+// fail: problems loading them are reported by the checks.
+func LoadEnv(cfg *config.Config) Env {
+	env := Env{Config: cfg}
+
+	_, store, err := companion.Resolve(cfg)
+	if err != nil {
+		env.AccountsErr = err
+		return env
+	}
+	env.Accounts = store
+
+	if account, err := store.ActiveAccount(); err == nil && account != nil {
+		if client, err := api.InitClient(account.Host, account.SessionToken); err == nil {
+			env.API = client
+		}
+	}
+	return env
+}
+
+const (
+	// skewWarn and skewFail bound the acceptable difference between the
+	// local and the server clock.
+	skewWarn = 30 * time.Second
+	skewFail = 5 * time.Minute
+)
+
+// Run runs every check in order and returns their results.
+func Run(env Env) []Result {
+	var results []Result
+	add := func(r ...Result) { results = append(results, r...) }
+
+	add(checkConfigFile())
+	add(checkHooks())
+	add(checkAccounts(env))
+
+	account := activeAccount(env)
+	serverUp := false
+	if env.API == nil || account == nil {
+		add(Result{Check: "server", Status: Skip, Detail: "not logged in"})
+	} else {
+		r := checkServer(env.API)
+		serverUp = r.Status == Pass
+		add(r)
+	}
+	if serverUp {
+		add(checkClock(env.API))
+		add(checkSession(env.API))
+		add(checkOlmCredentials(env.API, account))
+	}
+
+	running := olm.RunningInstances()
+	add(checkSockets(running)...)
+	add(systemChecks(running)...)
+	add(checkInterfaces(running)...)
+	add(checkMatchDomains(env.Config, running)...)
+
+	return results
+}
+
+func activeAccount(env Env) *config.Account {
+	if env.Accounts == nil {
+		return nil
+	}
+	account, err := env.Accounts.ActiveAccount()
+	if err != nil {
+		return nil
+	}
+	return account
+}
+
+func checkConfigFile() Result {
+	r := Result{Check: "config file"}
+	path, err := config.ConfigFilePath()
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.Status, r.Detail = Pass, "not present; using defaults"
+		return r
+	}
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		r.Fix = "Check the permissions of " + path
+		return r
+	}
+	var v map[string]any
+	if err := json.Unmarshal(data, &v); err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", path, err)
+		r.Fix = "Fix the JSON syntax or move the file aside to start from defaults"
+		return r
+	}
+	r.Status, r.Detail = Pass, path
+	return r
+}
+
+// checkHooks reports a hooks file the client ignores, as it does when the
+// file's owner or permissions would let someone other than root change it.
+func checkHooks() Result {
+	r := Result{Check: "hooks"}
+	path := config.HooksFilePath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		r.Status, r.Detail = Pass, "none configured"
+		return r
+	}
+
+	hooks, err := config.LoadHooks()
+	if err != nil {
+		r.Status, r.Detail = Warn, fmt.Sprintf("hooks are ignored: %v", err)
+		if runtime.GOOS == "windows" {
+			r.Fix = "Fix the JSON syntax of " + path
+		} else {
+			r.Fix = fmt.Sprintf("Make %s and the directories above it owned by root and writable only by root (sudo chown root:root, sudo chmod go-w), or fix its JSON syntax", path)
+		}
+		return r
+	}
+	r.Status, r.Detail = Pass, fmt.Sprintf("%d configured in %s", len(hooks.Commands()), path)
+	return r
+}
+
+func checkAccounts(env Env) Result {
+	r := Result{Check: "accounts"}
+	if env.AccountsErr != nil {
+		r.Status, r.Detail = Fail, env.AccountsErr.Error()
+		r.Fix = "Fix or remove accounts.json in the config directory, then run `pangolin login`"
+		return r
+	}
+	account := activeAccount(env)
+	if account == nil {
+		r.Status, r.Detail = Warn, "no account is logged in"
+		r.Fix = "Run `pangolin login`"
+		return r
+	}
+	r.Status, r.Detail = Pass, fmt.Sprintf("%s on %s", account.Email, account.Host)
+	if account.OrgID == "" {
+		r.Status = Warn
+		r.Detail += "; no organization selected"
+		r.Fix = "Run `pangolin select org`"
+	}
+	return r
+}
+
+func checkServer(client *api.Client) Result {
+	r := Result{Check: "server"}
+	if ok, err := client.CheckHealth(); !ok {
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s: %v", client.BaseURL, err)
+		r.Fix = "Check the network connection, proxy settings and firewall, and that the server address is correct"
+		return r
+	}
+	r.Status, r.Detail = Pass, client.BaseURL
+	return r
+}
+
+func checkClock(client *api.Client) Result {
+	r := Result{Check: "clock"}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	sent := time.Now()
+	resp, err := httpClient.Head(client.BaseURL)
+	if err != nil {
+		r.Status, r.Detail = Skip, err.Error()
+		return r
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		r.Status, r.Detail = Skip, "the server did not send its time"
+		return r
+	}
+
+	// The Date header has a resolution of one second and was produced
+	// somewhere during the round trip.
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := local.Sub(serverTime).Round(time.Second)
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs >= skewFail:
+		r.Status = Fail
+	case abs >= skewWarn:
+		r.Status = Warn
+	default:
+		r.Status = Pass
+	}
+	r.Detail = fmt.Sprintf("local clock differs from the server by %s", skew)
+	if r.Status != Pass {
+		r.Fix = "Enable time synchronization (for example `timedatectl set-ntp true`)"
+	}
+	return r
+}
+
+func checkSession(client *api.Client) Result {
+	r := Result{Check: "session"}
+	user, err := client.GetUser()
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		var apiErr *api.ErrorResponse
+		if errors.As(err, &apiErr) {
+			r.Fix = "Your session is no longer valid; run `pangolin login`"
+		}
+		return r
+	}
+	r.Status, r.Detail = Pass, "signed in as "+user.Email
+	return r
+}
+
+func checkOlmCredentials(client *api.Client, account *config.Account) Result {
+	r := Result{Check: "client credentials"}
+	creds := account.OlmCredentials
+	if creds == nil || creds.ID == "" {
+		r.Status, r.Detail = Warn, "no client credentials yet"
+		r.Fix = "They are created by the next `pangolin up`"
+		return r
+	}
+	if creds.Secret == "" {
+		r.Status, r.Detail = Warn, "the client secret could not be read from the secret backend"
+		r.Fix = "Unlock the keyring or check the secret_backend setting"
+		return r
+	}
+	if _, err := client.GetUserOlm(account.UserID, creds.ID, account.OrgID); err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		r.Fix = "The server no longer knows this client; `pangolin up` will register a new one"
+		return r
+	}
+	r.Status, r.Detail = Pass, creds.ID
+	return r
+}
+
+func checkSockets(running []olm.Instance) []Result {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	var results []Result
+	seen := map[string]bool{}
+	for _, inst := range running {
+		seen[inst.SocketPath] = true
+		results = append(results, Result{
+			Check:  "client " + inst.DisplayName(),
+			Status: Pass,
+			Detail: "running, control socket " + inst.SocketPath,
+		})
+	}
+
+	// A socket that exists without a client answering on it was left
+	// behind by a client that did not shut down cleanly.
+	def := olm.NewInstance("")
+	if !seen[def.SocketPath] {
+		r := Result{Check: "client default"}
+		info, err := os.Stat(def.SocketPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			r.Status, r.Detail = Pass, "not running"
+		case errors.Is(err, os.ErrPermission):
+			r.Status, r.Detail = Warn, err.Error()
+			r.Fix = "Rerun with sudo to inspect the client"
+		case err != nil:
+			r.Status, r.Detail = Warn, err.Error()
+		case info.Mode()&os.ModeSocket == 0:
+			r.Status, r.Detail = Fail, def.SocketPath+" is not a socket"
+			r.Fix = "Remove " + def.SocketPath
+		default:
+			r.Status, r.Detail = Warn, def.SocketPath+" exists but no client answers on it"
+			r.Fix = "Run `pangolin down`, or remove the stale socket with `sudo rm " + def.SocketPath + "`"
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// tunnelSettings is the part of the status' network settings the checks
+// use.
+type tunnelSettings struct {
+	IPv4Addresses []string `json:"ipv4_addresses"`
+	IPv4Routes    []struct {
+		Destination string `json:"destination_address"`
+		Mask        string `json:"subnet_mask"`
+	} `json:"ipv4_included_routes"`
+}
+
+func settingsOf(status *olm.StatusResponse) tunnelSettings {
+	var s tunnelSettings
+	if data, err := json.Marshal(status.NetworkSettings); err == nil {
+		_ = json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+// interfaceWithAddr returns the local interface that holds addr.
+func interfaceWithAddr(addr string) (*net.Interface, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", addr)
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func checkInterfaces(running []olm.Instance) []Result {
+	var results []Result
+	for _, inst := range running {
+		r := Result{Check: "interface " + inst.DisplayName()}
+		status, err := inst.Client().GetStatus()
+		if err != nil {
+			r.Status, r.Detail = Warn, err.Error()
+			results = append(results, r)
+			continue
+		}
+		settings := settingsOf(status)
+		if len(settings.IPv4Addresses) == 0 {
+			r.Status, r.Detail = Skip, "the client has not configured the tunnel yet"
+			results = append(results, r)
+			continue
+		}
+
+		addr, _, _ := strings.Cut(settings.IPv4Addresses[0], "/")
+		iface, err := interfaceWithAddr(addr)
+		switch {
+		case err != nil:
+			r.Status, r.Detail = Warn, err.Error()
+		case iface == nil:
+			r.Status, r.Detail = Fail, "no interface holds the tunnel address "+addr
+			r.Fix = "Restart the client with `pangolin down` and `pangolin up`"
+		case iface.Flags&net.FlagUp == 0:
+			r.Status, r.Detail = Fail, iface.Name+" is down"
+			r.Fix = "Restart the client with `pangolin down` and `pangolin up`"
+		default:
+			r.Status, r.Detail = Pass, fmt.Sprintf("%s has %s", iface.Name, addr)
+			if routes := checkRoutes(inst, iface.Name, settings); routes != nil {
+				results = append(results, r, *routes)
+				continue
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// checkMatchDomains resolves the configured match domains that are plain
+// host names; patterns with wildcards cannot be looked up.
+func checkMatchDomains(cfg *config.Config, running []olm.Instance) []Result {
+	if cfg == nil || len(cfg.Up.MatchDomains) == 0 || len(running) == 0 {
+		return nil
+	}
+
+	var names, patterns []string
+	for _, d := range cfg.Up.MatchDomains {
+		if strings.ContainsAny(d, "*?") {
+			patterns = append(patterns, d)
+		} else {
+			names = append(names, strings.TrimSuffix(d, "."))
+		}
+	}
+
+	var results []Result
+	for _, name := range names {
+		r := Result{Check: "resolve " + name}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		addrs, err := net.DefaultResolver.LookupHost(ctx, name)
+		cancel()
+		if err != nil {
+			r.Status, r.Detail = Fail, err.Error()
+			r.Fix = "Check that tunnel DNS is enabled (`pangolin up --tunnel-dns`) and that the name exists"
+		} else {
+			r.Status, r.Detail = Pass, strings.Join(addrs, ", ")
+		}
+		results = append(results, r)
+	}
+	if len(patterns) > 0 {
+		results = append(results, Result{
+			Check:  "match domains",
+			Status: Skip,
+			Detail: "wildcard patterns cannot be resolved: " + strings.Join(patterns, ", "),
+		})
+	}
+	return results
+}
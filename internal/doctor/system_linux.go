@@ -0,0 +1,137 @@
+package doctor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/fosrl/cli/internal/olm"
+)
+
+// Files the client's DNS override leaves behind while it is installed.
+var dnsOverrideFiles = []string{
+	"/etc/resolv.conf.olm.backup",
+	"/etc/NetworkManager/conf.d/olm-dns.conf",
+}
+
+func systemChecks(running []olm.Instance) []Result {
+	return []Result{checkTun(), checkStaleDNS(running)}
+}
+
+func checkTun() Result {
+	r := Result{Check: "tun device"}
+	info, err := os.Stat("/dev/net/tun")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		r.Status, r.Detail = Fail, "/dev/net/tun does not exist"
+		r.Fix = "Load the tun module (`sudo modprobe tun`); in a container, pass --device /dev/net/tun"
+	case err != nil:
+		r.Status, r.Detail = Fail, err.Error()
+	case info.Mode()&os.ModeCharDevice == 0:
+		r.Status, r.Detail = Fail, "/dev/net/tun is not a character device"
+	default:
+		r.Status, r.Detail = Pass, "/dev/net/tun"
+	}
+	return r
+}
+
+func checkStaleDNS(running []olm.Instance) Result {
+	r := Result{Check: "dns override"}
+
+	var found []string
+	for _, path := range dnsOverrideFiles {
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+
+	switch {
+	case len(found) == 0:
+		r.Status, r.Detail = Pass, "no override installed"
+	case len(running) > 0:
+		r.Status, r.Detail = Pass, "installed by the running client"
+	default:
+		r.Status = Fail
+		r.Detail = "a DNS override is installed but no client is running: " + strings.Join(found, ", ")
+		r.Fix = "Run `sudo pangolin reset-dns` to restore the system DNS configuration"
+	}
+	return r
+}
+
+// checkRoutes reports whether the routes the client was given are in the
+// main routing table on iface.
+func checkRoutes(inst olm.Instance, iface string, settings tunnelSettings) *Result {
+	if len(settings.IPv4Routes) == 0 {
+		return nil
+	}
+	r := &Result{Check: "routes " + inst.DisplayName()}
+
+	installed, err := kernelRoutes(iface)
+	if err != nil {
+		r.Status, r.Detail = Skip, err.Error()
+		return r
+	}
+
+	var missing []string
+	for _, route := range settings.IPv4Routes {
+		key, ok := routeKey(route.Destination, route.Mask)
+		if !ok {
+			continue
+		}
+		if !installed[key] {
+			missing = append(missing, route.Destination)
+		}
+	}
+
+	if len(missing) > 0 {
+		r.Status = Warn
+		r.Detail = fmt.Sprintf("%d of %d routes are not in the main table on %s: %s",
+			len(missing), len(settings.IPv4Routes), iface, strings.Join(missing, ", "))
+		r.Fix = "Check for conflicting routes with `ip route`, or restart the client"
+		return r
+	}
+	r.Status, r.Detail = Pass, fmt.Sprintf("%d routes on %s", len(settings.IPv4Routes), iface)
+	return r
+}
+
+// kernelRoutes returns the IPv4 routes on iface from /proc/net/route, keyed
+// as by routeKey.
+func kernelRoutes(iface string) (map[string]bool, error) {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	routes := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] != iface {
+			continue
+		}
+		routes[fields[1]+"/"+fields[7]] = true
+	}
+	return routes, scanner.Err()
+}
+
+// routeKey formats a destination and mask as /proc/net/route does: each as
+// a little-endian hexadecimal word.
+func routeKey(dest, mask string) (string, bool) {
+	d := net.ParseIP(dest).To4()
+	if d == nil {
+		return "", false
+	}
+	m := net.IPv4(255, 255, 255, 255).To4()
+	if mask != "" {
+		if m = net.ParseIP(mask).To4(); m == nil {
+			return "", false
+		}
+	}
+	d = d.Mask(net.IPMask(m))
+	return fmt.Sprintf("%08X/%08X", binary.LittleEndian.Uint32(d), binary.LittleEndian.Uint32(m)), true
+}
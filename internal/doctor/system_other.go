@@ -0,0 +1,13 @@
+//go:build !linux
+
+package doctor
+
+import "github.com/fosrl/cli/internal/olm"
+
+func systemChecks(running []olm.Instance) []Result {
+	return nil
+}
+
+func checkRoutes(inst olm.Instance, iface string, settings tunnelSettings) *Result {
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package events derives connection events from successive client status
+// snapshots and keeps them in a JSON Lines journal.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fosrl/cli/internal/olm"
+)
+
+// Event types.
+const (
+	ClientStarted   = "client_started"
+	ClientStopped   = "client_stopped"
+	Connected       = "connected"
+	Disconnected    = "disconnected"
+	Registered      = "registered"
+	Unregistered    = "unregistered"
+	Error           = "error"
+	OrgChanged      = "org_changed"
+	PeerAdded       = "peer_added"
+	PeerRemoved     = "peer_removed"
+	PeerUp          = "peer_up"
+	PeerDown        = "peer_down"
+	PeerPathChanged = "peer_path_changed"
+)
+
+// Event is a single state transition of a client.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Instance string    `json:"instance"`
+	Type     string    `json:"type"`
+	OrgID    string    `json:"orgId,omitempty"`
+	SiteID   int       `json:"siteId,omitempty"`
+	Site     string    `json:"site,omitempty"`
+	// From and To hold the previous and new value of whatever changed,
+	// such as the path to a peer ("direct", "relay" or "local").
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Diff returns the events that lead from prev to next. A nil prev is
+// treated as a client with no state, so that the first snapshot reports
+// what is already up.
+func Diff(prev, next *olm.StatusResponse) []Event {
+	if prev == nil {
+		prev = &olm.StatusResponse{}
+	}
+	if next == nil {
+		next = &olm.StatusResponse{}
+	}
+
+	var out []Event
+	org := next.OrgID
+
+	if prev.OrgID != "" && next.OrgID != "" && prev.OrgID != next.OrgID {
+		out = append(out, Event{Type: OrgChanged, OrgID: org, From: prev.OrgID, To: next.OrgID})
+	}
+	if prev.Registered != next.Registered {
+		out = append(out, Event{Type: pick(next.Registered, Registered, Unregistered), OrgID: org})
+	}
+	if prev.Connected != next.Connected {
+		out = append(out, Event{Type: pick(next.Connected, Connected, Disconnected), OrgID: org})
+	}
+	if next.Error != nil && (prev.Error == nil || *prev.Error != *next.Error) {
+		out = append(out, Event{Type: Error, OrgID: org, To: next.Error.Code, Message: next.Error.Message})
+	}
+
+	ids := make([]int, 0, len(prev.PeerStatuses)+len(next.PeerStatuses))
+	for id := range prev.PeerStatuses {
+		ids = append(ids, id)
+	}
+	for id := range next.PeerStatuses {
+		if _, ok := prev.PeerStatuses[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		p, n := prev.PeerStatuses[id], next.PeerStatuses[id]
+		switch {
+		case p == nil:
+			out = append(out, peerEvent(PeerAdded, org, n))
+			if n.Connected {
+				e := peerEvent(PeerUp, org, n)
+				e.To = Path(n)
+				out = append(out, e)
+			}
+		case n == nil:
+			out = append(out, peerEvent(PeerRemoved, org, p))
+		case p.Connected != n.Connected:
+			e := peerEvent(pick(n.Connected, PeerUp, PeerDown), org, n)
+			if n.Connected {
+				e.To = Path(n)
+			}
+			out = append(out, e)
+		case n.Connected && Path(p) != Path(n):
+			e := peerEvent(PeerPathChanged, org, n)
+			e.From, e.To = Path(p), Path(n)
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Path describes how traffic reaches a peer: "local", "relay" or "direct".
+func Path(p *olm.OLMPeerStatus) string {
+	switch {
+	case p.IsLocal:
+		return "local"
+	case p.IsRelay:
+		return "relay"
+	default:
+		return "direct"
+	}
+}
+
+func peerEvent(typ, org string, p *olm.OLMPeerStatus) Event {
+	return Event{Type: typ, OrgID: org, SiteID: p.SiteID, Site: p.SiteName}
+}
+
+func pick(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
+
+// Watch polls client every interval and calls emit with the events between
+// successive snapshots, stamped with the time and instance name. It emits
+// ClientStarted first and ClientStopped when ctx is done.
+func Watch(ctx context.Context, client *olm.Client, instance string, interval time.Duration, emit func(Event)) {
+	send := func(e Event) {
+		e.Time = time.Now().UTC()
+		e.Instance = instance
+		emit(e)
+	}
+
+	send(Event{Type: ClientStarted})
+
+	var prev *olm.StatusResponse
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			stopped := Event{Type: ClientStopped}
+			if prev != nil {
+				stopped.OrgID = prev.OrgID
+			}
+			send(stopped)
+			return
+		case <-ticker.C:
+		}
+
+		status, err := client.GetStatus()
+		if err != nil {
+			// The API may not be up yet, or is shutting down.
+			continue
+		}
+		for _, e := range Diff(prev, status) {
+			send(e)
+		}
+		prev = status
+	}
+}
+
+// Summary is a one-line human-readable description of e.
+func (e Event) Summary() string {
+	switch e.Type {
+	case PeerPathChanged:
+		return fmt.Sprintf("%s: %s -> %s", e.Site, e.From, e.To)
+	case PeerUp:
+		return fmt.Sprintf("%s: up (%s)", e.Site, e.To)
+	case PeerDown, PeerAdded, PeerRemoved:
+		return e.Site
+	case OrgChanged:
+		return fmt.Sprintf("%s -> %s", e.From, e.To)
+	case Error:
+		if e.To != "" {
+			return fmt.Sprintf("%s: %s", e.To, e.Message)
+		}
+		return e.Message
+	default:
+		return e.OrgID
+	}
+}
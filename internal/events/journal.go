@@ -0,0 +1,179 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxJournalSize is the size at which a journal is rotated.
+	maxJournalSize = 5 << 20
+	// keptJournals is the number of rotated journals kept per instance.
+	keptJournals = 3
+)
+
+// JournalPath returns the journal of the named client instance in dir.
+func JournalPath(dir, instance string) string {
+	if instance == "" {
+		return filepath.Join(dir, "events.jsonl")
+	}
+	return filepath.Join(dir, "events-"+instance+".jsonl")
+}
+
+// Journal appends events to a JSON Lines file, rotating it to .1, .2, ...
+// once it grows past a few megabytes.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// OpenJournal returns the journal at path, creating its directory.
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create event journal directory: %w", err)
+	}
+	return &Journal{path: path}, nil
+}
+
+// Append writes e as one line.
+func (j *Journal) Append(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if info, err := os.Stat(j.path); err == nil && info.Size()+int64(len(line)) > maxJournalSize {
+		rotateJournal(j.path)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open event journal: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("write event journal: %w", err)
+	}
+	return f.Close()
+}
+
+func rotateJournal(path string) {
+	_ = os.Remove(fmt.Sprintf("%s.%d", path, keptJournals))
+	for i := keptJournals - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	_ = os.Rename(path, path+".1")
+}
+
+// Read returns the events journaled in dir at or after since, oldest first.
+// Lines that cannot be parsed are skipped.
+func Read(dir string, since time.Time) ([]Event, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "events*.jsonl*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Event
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		scanEvents(f, func(e Event) {
+			if !e.Time.Before(since) {
+				out = append(out, e)
+			}
+		})
+		f.Close()
+	}
+
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Time.Before(out[b].Time) })
+	return out, nil
+}
+
+func scanEvents(r io.Reader, fn func(Event)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.Type != "" {
+			fn(e)
+		}
+	}
+}
+
+// Follow calls fn with each event appended to the journals in dir from now
+// on, until ctx is done.
+func Follow(ctx context.Context, dir string, fn func(Event)) error {
+	offsets := map[string]int64{}
+	scan := func(initial bool) {
+		paths, _ := filepath.Glob(filepath.Join(dir, "events*.jsonl"))
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			// Journals created after the first scan are read from the start.
+			if initial {
+				offsets[path] = info.Size()
+				continue
+			}
+			offset := offsets[path]
+			if info.Size() < offset {
+				// Rotated since the last scan: finish the old file first.
+				readFrom(path+".1", offset, fn)
+				offset = 0
+			}
+			offsets[path] = readFrom(path, offset, fn)
+		}
+	}
+
+	scan(true)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			scan(false)
+		}
+	}
+}
+
+// readFrom passes the complete lines of path after offset to fn and
+// returns the offset following the last of them.
+func readFrom(path string, offset int64, fn func(Event)) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return offset
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return offset
+	}
+	end := bytes.LastIndexByte(data, '\n') + 1
+	scanEvents(bytes.NewReader(data[:end]), fn)
+	return offset + int64(end)
+}
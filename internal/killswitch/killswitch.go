@@ -0,0 +1,163 @@
+// Package killswitch installs firewall rules that stop traffic meant for the
+// tunnel from leaving by another interface while the tunnel is down. The
+// rules belong to the kernel rather than the client, so they stay in place
+// when the client crashes until they are removed explicitly.
+package killswitch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+// Mode selects which traffic the kill switch blocks.
+type Mode string
+
+const (
+	// ModeOff installs no rules.
+	ModeOff Mode = "off"
+	// ModeProtected blocks traffic to the organization's private ranges
+	// unless it goes through the tunnel interface.
+	ModeProtected Mode = "protected"
+	// ModeAll blocks all traffic that does not go through the tunnel
+	// interface, except what the tunnel itself needs.
+	ModeAll Mode = "all"
+)
+
+// Modes lists the accepted modes.
+var Modes = []Mode{ModeOff, ModeProtected, ModeAll}
+
+// ParseMode parses a mode name.
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return ModeOff, nil
+	}
+	if !slices.Contains(Modes, Mode(s)) {
+		return "", fmt.Errorf("invalid kill switch mode %q; use off, protected or all", s)
+	}
+	return Mode(s), nil
+}
+
+// ErrUnsupported is returned by Apply on platforms without a kill switch.
+var ErrUnsupported = errors.New("the kill switch is not supported on this platform")
+
+// interfaceNamePattern matches the interface names the rules accept: the
+// characters Linux allows, up to its 15-character limit. The name ends up
+// in nft scripts and iptables rules, so nothing else may pass.
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+
+// ValidateInterface reports an error when iface is not a valid interface
+// name.
+func ValidateInterface(iface string) error {
+	if !interfaceNamePattern.MatchString(iface) {
+		return fmt.Errorf("kill switch: invalid interface name %q", iface)
+	}
+	return nil
+}
+
+// Rules describes the kill switch of one tunnel interface.
+type Rules struct {
+	Interface string `json:"interface"`
+	Mode      Mode   `json:"mode"`
+	// Protected are the ranges reached through the tunnel; traffic to them
+	// is dropped unless it leaves by Interface.
+	Protected []netip.Prefix `json:"protected,omitempty"`
+	// Allowed are the ranges that may be reached outside the tunnel even
+	// when protected: the server, the peers' endpoints and the routes
+	// excluded from the tunnel.
+	Allowed []netip.Prefix `json:"allowed,omitempty"`
+}
+
+// Equal reports whether r and o install the same rules.
+func (r Rules) Equal(o Rules) bool {
+	r, o = r.normalize(), o.normalize()
+	return r.Interface == o.Interface && r.Mode == o.Mode &&
+		slices.Equal(r.Protected, o.Protected) && slices.Equal(r.Allowed, o.Allowed)
+}
+
+func (r Rules) normalize() Rules {
+	r.Protected = collapse(r.Protected)
+	r.Allowed = collapse(r.Allowed)
+	return r
+}
+
+// collapse sorts prefixes and drops those covered by others, which nftables
+// rejects as overlapping set elements.
+func collapse(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p := netip.PrefixFrom(p.Addr().Unmap(), p.Bits()); p.IsValid() {
+			sorted = append(sorted, p.Masked())
+		}
+	}
+	slices.SortFunc(sorted, func(a, b netip.Prefix) int {
+		if a.Bits() != b.Bits() {
+			return a.Bits() - b.Bits()
+		}
+		return a.Addr().Compare(b.Addr())
+	})
+	var out []netip.Prefix
+	for _, p := range sorted {
+		covered := slices.ContainsFunc(out, func(q netip.Prefix) bool {
+			return q.Contains(p.Addr())
+		})
+		if !covered {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// stateDir holds the recorded rules. Only root can write to it, as the
+// watchdog installs whatever is recorded there. Like the rules themselves,
+// it does not survive a reboot.
+const stateDir = "/run/pangolin"
+
+// StatePath returns the file recording the rules installed for iface, from
+// which they are reinstated after the client dies.
+func StatePath(iface string) string {
+	return filepath.Join(stateDir, "killswitch-"+iface+".json")
+}
+
+// StatePaths returns the files recording the rules of every interface that
+// has a kill switch installed, including those of clients that died.
+func StatePaths() ([]string, error) {
+	return filepath.Glob(filepath.Join(stateDir, "killswitch-*.json"))
+}
+
+// Save records r at path.
+func Save(path string, r Rules) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load returns the rules recorded at path, or nil when there are none.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,230 @@
+//go:build linux
+
+package killswitch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"strings"
+)
+
+// Apply installs r, replacing the rules installed earlier for the same
+// interface in one step so that nothing leaks in between. It uses nftables
+// when available and iptables otherwise.
+func Apply(r Rules) error {
+	if err := ValidateInterface(r.Interface); err != nil {
+		return err
+	}
+	r = r.normalize()
+	if r.Mode == ModeOff {
+		_, err := Remove(r.Interface)
+		return err
+	}
+	if _, err := exec.LookPath("nft"); err == nil {
+		return run(nftScript(r), "nft", "-f", "-")
+	}
+	if _, err := exec.LookPath("iptables-restore"); err == nil {
+		return applyIptables(r)
+	}
+	return errors.New("kill switch: neither nft nor iptables is installed")
+}
+
+// Remove deletes the rules installed for iface by either backend. It
+// reports whether there were any.
+func Remove(iface string) (bool, error) {
+	if err := ValidateInterface(iface); err != nil {
+		return false, err
+	}
+	var removed bool
+	var errs []error
+	if _, err := exec.LookPath("nft"); err == nil {
+		table := nftTable(iface)
+		if exec.Command("nft", "list", "table", "inet", table).Run() == nil {
+			if err := run("", "nft", "delete", "table", "inet", table); err != nil {
+				errs = append(errs, err)
+			} else {
+				removed = true
+			}
+		}
+	}
+	for _, bin := range []string{"iptables", "ip6tables"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			continue
+		}
+		ok, err := removeIptables(bin, iptablesChain(iface))
+		removed = removed || ok
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+func nftTable(iface string) string {
+	return "pangolin_killswitch_" + strings.Map(func(c rune) rune {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			return c
+		}
+		return '_'
+	}, iface)
+}
+
+// nftScript returns an nft script that replaces the table for r's interface.
+// Declaring the table before deleting it makes the delete succeed when the
+// table does not exist yet; nft applies the script as one transaction.
+func nftScript(r Rules) string {
+	table := nftTable(r.Interface)
+	var b strings.Builder
+	fmt.Fprintf(&b, "table inet %s\n", table)
+	fmt.Fprintf(&b, "delete table inet %s\n", table)
+	fmt.Fprintf(&b, "table inet %s {\n", table)
+	b.WriteString("\tchain filter {\n")
+	b.WriteString("\t\toifname \"lo\" accept\n")
+	fmt.Fprintf(&b, "\t\toifname %q accept\n", r.Interface)
+	allowed4, allowed6 := splitPrefixes(r.Allowed)
+	if len(allowed4) > 0 {
+		fmt.Fprintf(&b, "\t\tip daddr { %s } accept\n", strings.Join(allowed4, ", "))
+	}
+	if len(allowed6) > 0 {
+		fmt.Fprintf(&b, "\t\tip6 daddr { %s } accept\n", strings.Join(allowed6, ", "))
+	}
+	protected4, protected6 := splitPrefixes(r.Protected)
+	if len(protected4) > 0 {
+		fmt.Fprintf(&b, "\t\tip daddr { %s } drop\n", strings.Join(protected4, ", "))
+	}
+	if len(protected6) > 0 {
+		fmt.Fprintf(&b, "\t\tip6 daddr { %s } drop\n", strings.Join(protected6, ", "))
+	}
+	if r.Mode == ModeAll {
+		// Keep DHCP and IPv6 neighbour discovery working so the host can
+		// still join a network and bring the tunnel up.
+		b.WriteString("\t\tudp sport 68 udp dport 67 accept\n")
+		b.WriteString("\t\ticmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept\n")
+		b.WriteString("\t\tdrop\n")
+	}
+	b.WriteString("\t}\n")
+	for _, hook := range []string{"output", "forward"} {
+		fmt.Fprintf(&b, "\tchain %s {\n", hook)
+		fmt.Fprintf(&b, "\t\ttype filter hook %s priority filter; policy accept;\n", hook)
+		b.WriteString("\t\tjump filter\n")
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// iptablesChain is at most 27 characters, within the 28 iptables allows.
+func iptablesChain(iface string) string {
+	return "PANGOLIN-KS-" + iface
+}
+
+// applyIptables fills the interface's chain with iptables-restore, which
+// replaces its rules in one step, then jumps to it from OUTPUT and FORWARD.
+func applyIptables(r Rules) error {
+	chain := iptablesChain(r.Interface)
+	allowed4, allowed6 := splitPrefixes(r.Allowed)
+	protected4, protected6 := splitPrefixes(r.Protected)
+
+	families := []struct {
+		bin, restore       string
+		allowed, protected []string
+		extra              []string
+	}{
+		{"iptables", "iptables-restore", allowed4, protected4, []string{
+			"-p udp --sport 68 --dport 67 -j ACCEPT",
+		}},
+		{"ip6tables", "ip6tables-restore", allowed6, protected6, []string{
+			"-p ipv6-icmp --icmpv6-type router-solicitation -j ACCEPT",
+			"-p ipv6-icmp --icmpv6-type neighbour-solicitation -j ACCEPT",
+			"-p ipv6-icmp --icmpv6-type neighbour-advertisement -j ACCEPT",
+		}},
+	}
+	for _, f := range families {
+		if _, err := exec.LookPath(f.restore); err != nil {
+			if f.bin == "iptables" {
+				return err
+			}
+			// Without ip6tables there is no IPv6 filtering to add to.
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString("*filter\n")
+		fmt.Fprintf(&b, ":%s - [0:0]\n", chain)
+		fmt.Fprintf(&b, "-A %s -o lo -j ACCEPT\n", chain)
+		fmt.Fprintf(&b, "-A %s -o %s -j ACCEPT\n", chain, r.Interface)
+		for _, a := range f.allowed {
+			fmt.Fprintf(&b, "-A %s -d %s -j ACCEPT\n", chain, a)
+		}
+		for _, p := range f.protected {
+			fmt.Fprintf(&b, "-A %s -d %s -j DROP\n", chain, p)
+		}
+		if r.Mode == ModeAll {
+			for _, rule := range f.extra {
+				fmt.Fprintf(&b, "-A %s %s\n", chain, rule)
+			}
+			fmt.Fprintf(&b, "-A %s -j DROP\n", chain)
+		}
+		b.WriteString("COMMIT\n")
+
+		// With --noflush, declaring an existing chain flushes only that
+		// chain and leaves the rest of the table alone.
+		if err := run(b.String(), f.restore, "--noflush"); err != nil {
+			return err
+		}
+		for _, hook := range []string{"OUTPUT", "FORWARD"} {
+			if exec.Command(f.bin, "-C", hook, "-j", chain).Run() == nil {
+				continue
+			}
+			if err := run("", f.bin, "-I", hook, "1", "-j", chain); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func removeIptables(bin, chain string) (bool, error) {
+	if exec.Command(bin, "-n", "-L", chain).Run() != nil {
+		return false, nil
+	}
+	for _, hook := range []string{"OUTPUT", "FORWARD"} {
+		for exec.Command(bin, "-D", hook, "-j", chain).Run() == nil {
+			// Delete every jump, should one have been added twice.
+		}
+	}
+	if err := run("", bin, "-F", chain); err != nil {
+		return true, err
+	}
+	return true, run("", bin, "-X", chain)
+}
+
+func splitPrefixes(prefixes []netip.Prefix) (v4, v6 []string) {
+	for _, p := range prefixes {
+		if p.Addr().Is4() {
+			v4 = append(v4, p.String())
+		} else {
+			v6 = append(v6, p.String())
+		}
+	}
+	return v4, v6
+}
+
+// run runs name with args, feeding it stdin, and returns its error output
+// on failure.
+func run(stdin, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", name, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
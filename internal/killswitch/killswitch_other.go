@@ -0,0 +1,16 @@
+//go:build !linux
+
+package killswitch
+
+// Apply is unsupported outside Linux.
+func Apply(r Rules) error {
+	if r.Mode == ModeOff {
+		return nil
+	}
+	return ErrUnsupported
+}
+
+// Remove has nothing to remove outside Linux.
+func Remove(iface string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,151 @@
+// Package metrics exposes the status of running clients in the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fosrl/cli/internal/olm"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Sample is the status of one client instance at scrape time. Err is set
+// when the instance could not be queried.
+type Sample struct {
+	Instance string
+	Status   *olm.StatusResponse
+	Err      error
+}
+
+// Collect queries each instance for its status.
+func Collect(instances []olm.Instance) []Sample {
+	samples := make([]Sample, 0, len(instances))
+	for _, inst := range instances {
+		status, err := inst.Client().GetStatus()
+		samples = append(samples, Sample{Instance: inst.DisplayName(), Status: status, Err: err})
+	}
+	return samples
+}
+
+type metric struct {
+	name string
+	help string
+	rows []string
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	var b strings.Builder
+	b.WriteString(m.name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(labels[i])
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(labels[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	m.rows = append(m.rows, b.String())
+}
+
+// Write writes samples to w as gauges. Peer metrics are labelled with the
+// instance, site ID and site name so that alerts can target a single site.
+func Write(w io.Writer, samples []Sample, now time.Time) error {
+	var (
+		clientUp   = &metric{name: "pangolin_client_up", help: "Whether the client's control socket answered the status request."}
+		clientInfo = &metric{name: "pangolin_client_info", help: "Client agent, version and organization."}
+		connected  = &metric{name: "pangolin_client_connected", help: "Whether the client is connected to the server."}
+		registered = &metric{name: "pangolin_client_registered", help: "Whether the client is registered with the server."}
+		peers      = &metric{name: "pangolin_client_peers", help: "Number of peers the client knows about."}
+		peerUp     = &metric{name: "pangolin_peer_up", help: "Whether the tunnel to the site is connected."}
+		peerRelay  = &metric{name: "pangolin_peer_relay", help: "Whether traffic to the site goes through a relay."}
+		peerLocal  = &metric{name: "pangolin_peer_local", help: "Whether the site is reached through a local network endpoint."}
+		peerRTT    = &metric{name: "pangolin_peer_rtt_seconds", help: "Last measured round-trip time to the site."}
+		peerSeen   = &metric{name: "pangolin_peer_last_seen_seconds", help: "Seconds since the site was last seen."}
+	)
+
+	for _, s := range samples {
+		inst := []string{"instance", s.Instance}
+		if s.Err != nil || s.Status == nil {
+			clientUp.add(0, inst...)
+			continue
+		}
+		clientUp.add(1, inst...)
+
+		status := s.Status
+		clientInfo.add(1, "instance", s.Instance, "agent", status.Agent, "version", status.Version, "org", status.OrgID)
+		connected.add(boolValue(status.Connected), inst...)
+		registered.add(boolValue(status.Registered), inst...)
+		peers.add(float64(len(status.PeerStatuses)), inst...)
+
+		ids := make([]int, 0, len(status.PeerStatuses))
+		for id := range status.PeerStatuses {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			p := status.PeerStatuses[id]
+			labels := []string{"instance", s.Instance, "site_id", strconv.Itoa(p.SiteID), "site", p.SiteName}
+			peerUp.add(boolValue(p.Connected), labels...)
+			peerRelay.add(boolValue(p.IsRelay), labels...)
+			peerLocal.add(boolValue(p.IsLocal), labels...)
+			if p.RTT > 0 {
+				peerRTT.add(p.RTT.Seconds(), labels...)
+			}
+			if !p.LastSeen.IsZero() {
+				peerSeen.add(max(now.Sub(p.LastSeen).Seconds(), 0), labels...)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []*metric{clientUp, clientInfo, connected, registered, peers, peerUp, peerRelay, peerLocal, peerRTT, peerSeen} {
+		if len(m.rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+		for _, row := range m.rows {
+			bw.WriteString(row)
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics of the instances returned by instances, which
+// is called on every scrape.
+func Handler(instances func() []olm.Instance) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		samples := Collect(instances())
+		w.Header().Set("Content-Type", ContentType)
+		_ = Write(w, samples, time.Now())
+	})
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
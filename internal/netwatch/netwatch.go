@@ -0,0 +1,53 @@
+// Package netwatch reports changes to the host's network: addresses coming
+// and going and the default route moving, as happens on resume from sleep or
+// when switching Wi-Fi networks.
+package netwatch
+
+import (
+	"context"
+	"time"
+)
+
+// settleDelay is how long a burst of changes must be quiet before it is
+// reported once.
+const settleDelay = 2 * time.Second
+
+// Watch calls onChange after the host's addresses or default route change,
+// until ctx is done. Changes to the interface named ignore, such as the
+// tunnel's own, are not reported. It returns an error when changes cannot be
+// watched at all.
+func Watch(ctx context.Context, ignore string, onChange func()) error {
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	if err := watch(ctx, ignore, notify); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+			}
+			// Wait for the burst to settle.
+			for settled := false; !settled; {
+				select {
+				case <-ctx.Done():
+					return
+				case <-changed:
+				case <-time.After(settleDelay):
+					settled = true
+				}
+			}
+			onChange()
+		}
+	}()
+	return nil
+}
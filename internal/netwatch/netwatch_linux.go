@@ -0,0 +1,104 @@
+//go:build linux
+
+package netwatch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// watch subscribes to rtnetlink address and route notifications.
+func watch(ctx context.Context, ignore string, notify func()) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return fmt.Errorf("open netlink socket: %w", err)
+	}
+	groups := unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR | unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: uint32(groups)}); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("subscribe to netlink notifications: %w", err)
+	}
+	// Wake up regularly to notice ctx being done.
+	tv := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, 1<<16)
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+					continue
+				}
+				// ENOBUFS means notifications were dropped; treat it as a
+				// change rather than miss one.
+				if errors.Is(err, unix.ENOBUFS) {
+					notify()
+					continue
+				}
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for i := range msgs {
+				if relevant(&msgs[i], ignore) {
+					notify()
+					break
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// relevant reports whether m is an address change or a default route change
+// in the main table on an interface other than ignore.
+func relevant(m *syscall.NetlinkMessage, ignore string) bool {
+	switch m.Header.Type {
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		if len(m.Data) < unix.SizeofIfAddrmsg {
+			return false
+		}
+		return !ignored(int(binary.NativeEndian.Uint32(m.Data[4:8])), ignore)
+
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		if len(m.Data) < unix.SizeofRtMsg {
+			return false
+		}
+		dstLen, table := m.Data[1], m.Data[4]
+		if dstLen != 0 || table != unix.RT_TABLE_MAIN {
+			return false
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			return true
+		}
+		for _, a := range attrs {
+			if a.Attr.Type == unix.RTA_OIF && len(a.Value) >= 4 {
+				return !ignored(int(binary.NativeEndian.Uint32(a.Value)), ignore)
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func ignored(index int, ignore string) bool {
+	if ignore == "" {
+		return false
+	}
+	iface, err := net.InterfaceByIndex(index)
+	return err == nil && iface.Name == ignore
+}
@@ -0,0 +1,58 @@
+//go:build !linux
+
+package netwatch
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const pollInterval = 5 * time.Second
+
+// watch polls the interface addresses, as there is no portable change
+// notification.
+func watch(ctx context.Context, ignore string, notify func()) error {
+	last := snapshot(ignore)
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if current := snapshot(ignore); current != last {
+				last = current
+				notify()
+			}
+		}
+	}()
+	return nil
+}
+
+// snapshot lists the addresses of the interfaces that are up.
+func snapshot(ignore string) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	var entries []string
+	for _, iface := range ifaces {
+		if iface.Name == ignore || iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			entries = append(entries, iface.Name+" "+addr.String())
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\n")
+}
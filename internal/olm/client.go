@@ -36,6 +36,10 @@ type StatusResponse struct {
 	PeerStatuses    map[int]*OLMPeerStatus `json:"peers,omitempty"`
 	NetworkSettings map[string]interface{} `json:"networkSettings,omitempty"`
 	Error           *StatusError           `json:"error,omitempty"`
+
+	// Reconnect is filled in by the CLI from the supervisor's state file,
+	// not by the client.
+	Reconnect *ReconnectState `json:"reconnect,omitempty"`
 }
 
 // OLMPeerStatus represents the status of a peer connection
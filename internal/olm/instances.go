@@ -0,0 +1,104 @@
+package olm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// DefaultInterfaceName is the tunnel interface of the default instance.
+const DefaultInterfaceName = "pangolin"
+
+// Instance is one client, identified by name, with its own control socket,
+// interface and log file. The default instance has an empty name.
+type Instance struct {
+	Name       string
+	SocketPath string
+}
+
+// NewInstance returns the instance called name.
+func NewInstance(name string) Instance {
+	return Instance{Name: name, SocketPath: InstanceSocketPath(name)}
+}
+
+// Client returns a client for the instance's control socket.
+func (i Instance) Client() *Client {
+	return NewClient(i.SocketPath)
+}
+
+// DisplayName is the instance's name, or "default".
+func (i Instance) DisplayName() string {
+	if i.Name == "" {
+		return "default"
+	}
+	return i.Name
+}
+
+// RunningInstances returns the instances whose control socket responds,
+// the default instance first and the rest by name.
+func RunningInstances() []Instance {
+	var running []Instance
+	for _, inst := range knownInstances() {
+		if inst.Client().IsRunning() {
+			running = append(running, inst)
+		}
+	}
+	sort.SliceStable(running, func(a, b int) bool {
+		if running[a].Name == "" || running[b].Name == "" {
+			return running[a].Name == ""
+		}
+		return running[a].Name < running[b].Name
+	})
+	return running
+}
+
+// ClientForOrg returns a client for the running instance connected to
+// orgID. When no instance reports that organization it returns the default
+// instance's client, so callers behave as they do with a single client.
+func ClientForOrg(orgID string) *Client {
+	running := RunningInstances()
+	if orgID != "" && len(running) > 1 {
+		for _, inst := range running {
+			client := inst.Client()
+			if status, err := client.GetStatus(); err == nil && status.OrgID == orgID {
+				return client
+			}
+		}
+	}
+	if len(running) == 1 {
+		return running[0].Client()
+	}
+	return NewClient("")
+}
+
+// InstanceName turns s, such as a profile name or organization ID, into a
+// name usable in socket, interface and log file names.
+func InstanceName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// InstanceInterfaceName returns the default tunnel interface name for the
+// named instance. Linux limits interface names to 15 bytes, so long
+// instance names are shortened with a hash to keep them distinct.
+func InstanceInterfaceName(name string) string {
+	if name == "" {
+		return DefaultInterfaceName
+	}
+	const prefix, maxLen = "pg-", 15
+	if len(prefix)+len(name) <= maxLen {
+		return prefix + name
+	}
+	sum := sha256.Sum256([]byte(name))
+	suffix := hex.EncodeToString(sum[:])[:4]
+	return prefix + strings.TrimRight(name[:maxLen-len(prefix)-len(suffix)-1], "-") + "-" + suffix
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package olm
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// InstanceSocketPath returns the control socket of the named instance;
+// named instances sit next to the default socket.
+func InstanceSocketPath(name string) string {
+	if name == "" {
+		return defaultSocketPath
+	}
+	return filepath.Join(filepath.Dir(defaultSocketPath), "olm-"+name+".sock")
+}
+
+// knownInstances returns the default instance and every named instance with
+// a socket file, running or not.
+func knownInstances() []Instance {
+	instances := []Instance{NewInstance("")}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(defaultSocketPath), "olm-*.sock"))
+	for _, m := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "olm-"), ".sock")
+		instances = append(instances, Instance{Name: name, SocketPath: m})
+	}
+	return instances
+}
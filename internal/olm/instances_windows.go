@@ -0,0 +1,17 @@
+//go:build windows
+
+package olm
+
+// InstanceSocketPath returns the named pipe of the named instance.
+func InstanceSocketPath(name string) string {
+	if name == "" {
+		return defaultSocketPath
+	}
+	return defaultSocketPath + "-" + name
+}
+
+// knownInstances returns only the default instance: named pipes cannot be
+// listed cheaply, and clients on Windows are run by the desktop app.
+func knownInstances() []Instance {
+	return []Instance{NewInstance("")}
+}
@@ -0,0 +1,58 @@
+package olm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReconnectState is what the client's supervisor reports while it restarts
+// a tunnel that was terminated or lost its credentials.
+type ReconnectState struct {
+	Reconnecting bool      `json:"reconnecting"`
+	Attempt      int       `json:"attempt"`
+	Reason       string    `json:"reason,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+	Since        time.Time `json:"since,omitzero"`
+	NextAttempt  time.Time `json:"nextAttempt,omitzero"`
+}
+
+// ReconnectStatePath returns the reconnect state file of the named client
+// instance in dir.
+func ReconnectStatePath(dir, instance string) string {
+	if instance == "" {
+		return filepath.Join(dir, "reconnect.json")
+	}
+	return filepath.Join(dir, "reconnect-"+instance+".json")
+}
+
+// WriteReconnectState replaces the state file at path.
+func WriteReconnectState(path string, state ReconnectState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadReconnectState returns the state at path, or nil when there is none
+// or the supervisor is not reconnecting.
+func ReadReconnectState(path string) *ReconnectState {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var state ReconnectState
+	if json.Unmarshal(data, &state) != nil || !state.Reconnecting {
+		return nil
+	}
+	return &state
+}
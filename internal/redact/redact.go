@@ -0,0 +1,138 @@
+// Package redact strips credentials from data that leaves the machine,
+// such as support bundles.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces every redacted value.
+const Placeholder = "[REDACTED]"
+
+// minSecretLen is the length below which a known value is not replaced in
+// free text, where it would match too much unrelated data.
+const minSecretLen = 8
+
+// sensitiveKeyParts are substrings of JSON keys whose values are redacted
+// regardless of content. Keys are compared in lower case with '_' and '-'
+// removed.
+var sensitiveKeyParts = []string{"token", "secret", "password", "apikey", "privatekey", "cookie", "csrf"}
+
+// settingKeys name settings that match sensitiveKeyParts but hold no
+// credential.
+var settingKeys = map[string]bool{"secretbackend": true}
+
+// IsSensitiveKey reports whether values stored under key are redacted.
+func IsSensitiveKey(key string) bool {
+	k := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if settingKeys[k] {
+		return false
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(k, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor removes credentials from data: the values of sensitive JSON keys
+// and any occurrence of a known secret value.
+type Redactor struct {
+	secrets []string
+}
+
+// New returns a redactor that also removes each of the given secret values
+// wherever they occur. Empty and very short values are ignored.
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(secrets...)
+	return r
+}
+
+// Add registers more secret values.
+func (r *Redactor) Add(secrets ...string) {
+	for _, s := range secrets {
+		s = strings.TrimSpace(s)
+		if len(s) >= minSecretLen {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	// Replace longer values first so that one containing another is
+	// removed whole.
+	sort.Slice(r.secrets, func(a, b int) bool { return len(r.secrets[a]) > len(r.secrets[b]) })
+}
+
+// Text returns data with every known secret value replaced.
+func (r *Redactor) Text(data []byte) []byte {
+	for _, s := range r.secrets {
+		data = bytes.ReplaceAll(data, []byte(s), []byte(Placeholder))
+	}
+	return data
+}
+
+// JSON returns the JSON document data, indented, with the values of
+// sensitive keys and any known secret value replaced. Data that is not
+// valid JSON is an error, so that it is never passed through unredacted.
+func (r *Redactor) JSON(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(redactValue(v, false), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return r.Text(out), nil
+}
+
+// Value returns v, which must marshal to JSON, redacted as by JSON.
+func (r *Redactor) Value(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return r.JSON(data)
+}
+
+// Leaks reports whether data still contains a known secret value.
+func (r *Redactor) Leaks(data []byte) bool {
+	for _, s := range r.secrets {
+		if bytes.Contains(data, []byte(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue replaces the scalars in v that are stored under a sensitive
+// key, at any depth below it.
+func redactValue(v any, sensitive bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = redactValue(child, sensitive || IsSensitiveKey(k))
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue(child, sensitive)
+		}
+		return v
+	case nil:
+		return nil
+	case string:
+		if sensitive && v != "" {
+			return Placeholder
+		}
+		return v
+	default:
+		if sensitive {
+			return Placeholder
+		}
+		return v
+	}
+}
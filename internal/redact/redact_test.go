@@ -0,0 +1,145 @@
+package redact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"sessionToken", true},
+		{"session_token", true},
+		{"secret", true},
+		{"secretRef", true},
+		{"CLIENT_SECRET", true},
+		{"password", true},
+		{"api-key", true},
+		{"privateKey", true},
+		{"secretBackend", false},
+		{"secret_backend", false},
+		{"userId", false},
+		{"host", false},
+		{"id", false},
+	}
+	for _, tt := range tests {
+		if got := IsSensitiveKey(tt.key); got != tt.want {
+			t.Errorf("IsSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestJSONRedactsNestedValues(t *testing.T) {
+	in := `{
+		"secretBackend": "keyring",
+		"accounts": {
+			"u1": {
+				"userId": "u1",
+				"sessionToken": "abc",
+				"olmCredentials": {"id": "olm1", "secret": "def", "secretRef": "keyring:olm"}
+			}
+		},
+		"tokens": [{"value": "ghi", "expires": 3}, "jkl"],
+		"secrets": {"list": ["mno"], "count": 2, "empty": "", "none": null}
+	}`
+	out, err := New().JSON([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	account := got["accounts"].(map[string]any)["u1"].(map[string]any)
+	creds := account["olmCredentials"].(map[string]any)
+	checks := []struct {
+		name     string
+		got, want any
+	}{
+		{"secretBackend", got["secretBackend"], "keyring"},
+		{"userId", account["userId"], "u1"},
+		{"sessionToken", account["sessionToken"], Placeholder},
+		{"olm id", creds["id"], "olm1"},
+		{"olm secret", creds["secret"], Placeholder},
+		{"olm secretRef", creds["secretRef"], Placeholder},
+		{"tokens[0].value", got["tokens"].([]any)[0].(map[string]any)["value"], Placeholder},
+		{"tokens[0].expires", got["tokens"].([]any)[0].(map[string]any)["expires"], Placeholder},
+		{"tokens[1]", got["tokens"].([]any)[1], Placeholder},
+		{"secrets.list[0]", got["secrets"].(map[string]any)["list"].([]any)[0], Placeholder},
+		{"secrets.count", got["secrets"].(map[string]any)["count"], Placeholder},
+		{"secrets.empty", got["secrets"].(map[string]any)["empty"], ""},
+		{"secrets.none", got["secrets"].(map[string]any)["none"], nil},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestJSONRejectsInvalidInput(t *testing.T) {
+	for _, in := range []string{"", "sessionToken=abc", `{"sessionToken": "abc"`} {
+		if out, err := New().JSON([]byte(in)); err == nil {
+			t.Errorf("JSON(%q) = %q, want an error", in, out)
+		}
+	}
+}
+
+func TestJSONReplacesKnownValues(t *testing.T) {
+	const secret = "s3cr3t-value-1234"
+	out, err := New(secret).JSON([]byte(`{"log": "connected with ` + secret + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), secret) {
+		t.Errorf("JSON output still contains the secret: %s", out)
+	}
+}
+
+func TestText(t *testing.T) {
+	const (
+		short = "abcdefgh"
+		long  = "abcdefgh-ijklmnop"
+	)
+	r := New(short, long, "", "tiny", "  ")
+
+	got := string(r.Text([]byte("a=" + long + " b=" + short + " c=tiny")))
+	want := "a=" + Placeholder + " b=" + Placeholder + " c=tiny"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestTextReplacesLongestFirst(t *testing.T) {
+	const (
+		inner = "token-12345"
+		outer = "prefix-token-12345-suffix"
+	)
+	// Registered shortest first, and separately, to check the ordering is
+	// kept across Add calls.
+	r := New(inner)
+	r.Add(outer)
+
+	got := string(r.Text([]byte(outer)))
+	if got != Placeholder {
+		t.Errorf("Text() = %q, want %q", got, Placeholder)
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	const secret = "s3cr3t-value-1234"
+	r := New(secret)
+	if !r.Leaks([]byte("x " + secret + " y")) {
+		t.Error("Leaks() = false for data containing the secret")
+	}
+	if r.Leaks(r.Text([]byte("x " + secret + " y"))) {
+		t.Error("Leaks() = true for redacted data")
+	}
+	if New().Leaks([]byte(secret)) {
+		t.Error("Leaks() = true with no known secrets")
+	}
+}
@@ -0,0 +1,130 @@
+// Package service installs the client as a system service (a systemd unit
+// or an OpenRC script) so it is supervised, restarted when it crashes and
+// started at boot.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Name is the unit or init script name of the client service.
+const Name = "pangolin-client"
+
+// EnvFilePath holds the client credentials the service is started with. It
+// is readable by root only.
+const EnvFilePath = "/etc/pangolin/client.env"
+
+var (
+	// ErrUnsupported is returned when no supported service manager is found.
+	ErrUnsupported = errors.New("no supported service manager found (systemd or OpenRC is required)")
+
+	// ErrNotInstalled is returned when the client service is not installed.
+	ErrNotInstalled = errors.New("the client service is not installed; run `sudo pangolin service install`")
+)
+
+// Spec describes the service to install.
+type Spec struct {
+	// Executable is the absolute path of the pangolin binary.
+	Executable string
+	// Args are passed to Executable, such as up client --attach.
+	Args []string
+	// Env is written to EnvFilePath and exported to the service.
+	Env map[string]string
+}
+
+// Status describes the installed service.
+type Status struct {
+	Installed bool
+	Enabled   bool
+	Active    bool
+}
+
+// Manager installs and controls the client service with one init system.
+type Manager interface {
+	// Name is the init system, such as "systemd".
+	Name() string
+	// Path is the unit file or init script path.
+	Path() string
+	Install(spec Spec) error
+	Uninstall() error
+	Start() error
+	Stop() error
+	Status() (Status, error)
+}
+
+// Installed returns the service manager when the client service is
+// installed with it.
+func Installed() (Manager, bool) {
+	m, err := Detect()
+	if err != nil {
+		return nil, false
+	}
+	if _, err := os.Stat(m.Path()); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// writeEnvFile writes env as KEY='value' lines, which both systemd's
+// EnvironmentFile= and a POSIX shell read the same way.
+func writeEnvFile(env map[string]string) error {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# Written by pangolin service install; readable by root only.\n")
+	for _, k := range keys {
+		v := env[k]
+		if strings.ContainsAny(v, "'\n\\") {
+			return fmt.Errorf("value of %s contains characters that cannot be written to %s", k, EnvFilePath)
+		}
+		fmt.Fprintf(&b, "%s='%s'\n", k, v)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(EnvFilePath), 0o755); err != nil {
+		return err
+	}
+	// Write with restrictive permissions from the start so the secret is
+	// never world-readable, even briefly.
+	tmp := EnvFilePath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, EnvFilePath)
+}
+
+// privileged runs an init system command, through sudo when not root so the
+// password can be prompted for interactively.
+func privileged(name string, args ...string) error {
+	var c *exec.Cmd
+	if os.Geteuid() == 0 {
+		c = exec.Command(name, args...)
+	} else {
+		c = exec.Command("sudo", append([]string{name}, args...)...)
+		c.Stdin = os.Stdin
+	}
+	c.Stdout = os.Stderr
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// succeeds reports whether a query command exits with status 0.
+func succeeds(name string, args ...string) bool {
+	return exec.Command(name, args...).Run() == nil
+}
@@ -0,0 +1,200 @@
+//go:build linux
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	systemdUnitPath    = "/etc/systemd/system/" + Name + ".service"
+	openRCScriptPath   = "/etc/init.d/" + Name
+	openRCRunlevelLink = "/etc/runlevels/default/" + Name
+)
+
+// Detect returns the manager for the init system this host boots with.
+func Detect() (Manager, error) {
+	// sd_booted(3): systemd creates this directory early during boot.
+	if info, err := os.Stat("/run/systemd/system"); err == nil && info.IsDir() {
+		return systemd{}, nil
+	}
+	if _, err := os.Stat("/sbin/openrc-run"); err == nil {
+		return openRC{}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+type systemd struct{}
+
+func (systemd) Name() string { return "systemd" }
+func (systemd) Path() string { return systemdUnitPath }
+
+func (systemd) Install(spec Spec) error {
+	if err := writeEnvFile(spec.Env); err != nil {
+		return fmt.Errorf("write %s: %w", EnvFilePath, err)
+	}
+
+	words := make([]string, 0, len(spec.Args)+1)
+	for _, w := range append([]string{spec.Executable}, spec.Args...) {
+		words = append(words, systemdQuote(w))
+	}
+
+	unit := fmt.Sprintf(`[Unit]
+Description=Pangolin client
+Documentation=https://docs.pangolin.net
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+EnvironmentFile=%s
+ExecStart=%s
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, EnvFilePath, strings.Join(words, " "))
+
+	if err := os.WriteFile(systemdUnitPath, []byte(unit), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", systemdUnitPath, err)
+	}
+	if err := privileged("systemctl", "daemon-reload"); err != nil {
+		return err
+	}
+	return privileged("systemctl", "enable", Name)
+}
+
+func (systemd) Uninstall() error {
+	if succeeds("systemctl", "is-enabled", "--quiet", Name) || succeeds("systemctl", "is-active", "--quiet", Name) {
+		if err := privileged("systemctl", "disable", "--now", Name); err != nil {
+			return err
+		}
+	}
+	if err := removeIfExists(systemdUnitPath); err != nil {
+		return err
+	}
+	if err := removeIfExists(EnvFilePath); err != nil {
+		return err
+	}
+	return privileged("systemctl", "daemon-reload")
+}
+
+func (systemd) Start() error { return privileged("systemctl", "start", Name) }
+func (systemd) Stop() error  { return privileged("systemctl", "stop", Name) }
+
+func (systemd) Status() (Status, error) {
+	if _, err := os.Stat(systemdUnitPath); err != nil {
+		return Status{}, nil
+	}
+	return Status{
+		Installed: true,
+		Enabled:   succeeds("systemctl", "is-enabled", "--quiet", Name),
+		Active:    succeeds("systemctl", "is-active", "--quiet", Name),
+	}, nil
+}
+
+// systemdQuote quotes w for an ExecStart= line, escaping the specifier and
+// variable expansion characters systemd would otherwise interpret.
+func systemdQuote(w string) string {
+	w = strings.ReplaceAll(w, "%", "%%")
+	w = strings.ReplaceAll(w, "$", "$$")
+	if w != "" && !strings.ContainsAny(w, " \t\"'\\;") {
+		return w
+	}
+	w = strings.ReplaceAll(w, `\`, `\\`)
+	w = strings.ReplaceAll(w, `"`, `\"`)
+	return `"` + w + `"`
+}
+
+type openRC struct{}
+
+func (openRC) Name() string { return "openrc" }
+func (openRC) Path() string { return openRCScriptPath }
+
+func (openRC) Install(spec Spec) error {
+	if err := writeEnvFile(spec.Env); err != nil {
+		return fmt.Errorf("write %s: %w", EnvFilePath, err)
+	}
+
+	args := make([]string, 0, len(spec.Args))
+	for _, a := range spec.Args {
+		args = append(args, shellQuote(a))
+	}
+
+	// supervise-daemon restarts the client when it exits, after
+	// respawn_delay; output goes to syslog through logger(1).
+	script := fmt.Sprintf(`#!/sbin/openrc-run
+
+description="Pangolin client"
+supervisor=supervise-daemon
+command=%s
+command_args=%s
+respawn_delay=5
+respawn_max=0
+output_logger="logger -t %s"
+error_logger="logger -t %s -p daemon.err"
+
+depend() {
+	need net
+	after firewall
+}
+
+start_pre() {
+	set -a
+	. %s
+	set +a
+}
+`, shellQuote(spec.Executable), shellQuote(strings.Join(args, " ")), Name, Name, EnvFilePath)
+
+	if err := os.WriteFile(openRCScriptPath, []byte(script), 0o755); err != nil {
+		return fmt.Errorf("write %s: %w", openRCScriptPath, err)
+	}
+	return privileged("rc-update", "add", Name, "default")
+}
+
+func (openRC) Uninstall() error {
+	if succeeds("rc-service", Name, "status") {
+		if err := privileged("rc-service", Name, "stop"); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Lstat(openRCRunlevelLink); err == nil {
+		if err := privileged("rc-update", "del", Name, "default"); err != nil {
+			return err
+		}
+	}
+	if err := removeIfExists(openRCScriptPath); err != nil {
+		return err
+	}
+	return removeIfExists(EnvFilePath)
+}
+
+func (openRC) Start() error { return privileged("rc-service", Name, "start") }
+func (openRC) Stop() error  { return privileged("rc-service", Name, "stop") }
+
+func (openRC) Status() (Status, error) {
+	if _, err := os.Stat(openRCScriptPath); err != nil {
+		return Status{}, nil
+	}
+	_, linkErr := os.Lstat(openRCRunlevelLink)
+	return Status{
+		Installed: true,
+		Enabled:   linkErr == nil,
+		Active:    succeeds("rc-service", Name, "status"),
+	}, nil
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
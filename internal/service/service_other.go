@@ -0,0 +1,9 @@
+//go:build !linux
+
+package service
+
+// Detect returns ErrUnsupported; the client service is only available on
+// Linux.
+func Detect() (Manager, error) {
+	return nil, ErrUnsupported
+}
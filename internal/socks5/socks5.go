@@ -0,0 +1,180 @@
+// Package socks5 implements a minimal SOCKS5 server (RFC 1928): no
+// authentication and the CONNECT command only, with outbound connections
+// made through a caller-supplied dialer.
+package socks5
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	version5 = 0x05
+
+	authNone         = 0x00
+	authNoAcceptable = 0xff
+
+	cmdConnect = 0x01
+
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+
+	replySucceeded          = 0x00
+	replyGeneralFailure     = 0x01
+	replyHostUnreachable    = 0x04
+	replyCommandUnsupported = 0x07
+	replyAddressUnsupported = 0x08
+
+	handshakeTimeout = 30 * time.Second
+)
+
+// DialFunc opens the outbound connection for a CONNECT request.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Serve accepts SOCKS5 clients on l until it is closed, connecting each
+// CONNECT request through dial. It returns the error from Accept.
+func Serve(l net.Listener, dial DialFunc) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			_ = ServeConn(conn, dial)
+		}()
+	}
+}
+
+// ServeConn handles a single SOCKS5 client connection and closes it when done.
+func ServeConn(conn net.Conn, dial DialFunc) error {
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	target, err := handshake(conn)
+	if err != nil {
+		return err
+	}
+
+	upstream, err := dial(context.Background(), "tcp", target)
+	if err != nil {
+		_ = writeReply(conn, replyHostUnreachable)
+		return fmt.Errorf("connect %s: %w", target, err)
+	}
+	defer upstream.Close()
+
+	if err := writeReply(conn, replySucceeded); err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	Pipe(conn, upstream)
+	return nil
+}
+
+// handshake negotiates authentication and reads a CONNECT request,
+// returning the requested "host:port".
+func handshake(conn net.Conn) (string, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return "", err
+	}
+	if header[0] != version5 {
+		return "", fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+	method := byte(authNoAcceptable)
+	for _, m := range methods {
+		if m == authNone {
+			method = authNone
+			break
+		}
+	}
+	if _, err := conn.Write([]byte{version5, method}); err != nil {
+		return "", err
+	}
+	if method == authNoAcceptable {
+		return "", errors.New("client offered no supported authentication method")
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return "", err
+	}
+	if req[0] != version5 {
+		return "", fmt.Errorf("unsupported SOCKS version %d", req[0])
+	}
+	if req[1] != cmdConnect {
+		_ = writeReply(conn, replyCommandUnsupported)
+		return "", fmt.Errorf("unsupported SOCKS command %d", req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case atypIPv4:
+		var ip [4]byte
+		if _, err := io.ReadFull(conn, ip[:]); err != nil {
+			return "", err
+		}
+		host = net.IP(ip[:]).String()
+	case atypIPv6:
+		var ip [16]byte
+		if _, err := io.ReadFull(conn, ip[:]); err != nil {
+			return "", err
+		}
+		host = net.IP(ip[:]).String()
+	case atypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return "", err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		_ = writeReply(conn, replyAddressUnsupported)
+		return "", fmt.Errorf("unsupported SOCKS address type %d", req[3])
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// writeReply sends a reply with an unspecified bound address; clients do not
+// use it for CONNECT.
+func writeReply(conn net.Conn, code byte) error {
+	_, err := conn.Write([]byte{version5, code, 0x00, atypIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// Pipe copies data between a and b in both directions until either side is
+// done, then closes both.
+func Pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+	<-done
+}
@@ -0,0 +1,216 @@
+// Package sshcerts caches just-in-time SSH keys and certificates so repeated
+// ssh/scp invocations against the same resource can skip signing.
+package sshcerts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fosrl/cli/internal/api"
+	"github.com/fosrl/cli/internal/config"
+)
+
+const cacheDirName = "ssh-certs"
+
+// SafetyMargin is how long before expiry a cached certificate stops being
+// reused, so a connection does not start with a certificate that expires
+// during the handshake.
+const SafetyMargin = 2 * time.Minute
+
+// Entry is one cached key pair and certificate.
+type Entry struct {
+	UserID        string              `json:"userId"`
+	OrgID         string              `json:"orgId"`
+	Resource      string              `json:"resource"`
+	Username      string              `json:"username,omitempty"`
+	PrivateKeyPEM string              `json:"privateKey"`
+	PublicKey     string              `json:"publicKey"`
+	Certificate   string              `json:"certificate"`
+	SignData      *api.SignSSHKeyData `json:"signData"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	ExpiresAt     time.Time           `json:"expiresAt"`
+
+	path string
+}
+
+// Key identifies a cache entry.
+type Key struct {
+	UserID   string
+	OrgID    string
+	Resource string
+	Username string
+}
+
+func (k Key) fileName() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{k.UserID, k.OrgID, k.Resource, k.Username}, "\x00")))
+	return hex.EncodeToString(sum[:16]) + ".json"
+}
+
+// Path returns the path of the cached entry file.
+func (e *Entry) Path() string {
+	return e.path
+}
+
+// Valid reports whether the certificate can still be used at now.
+func (e *Entry) Valid(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.Add(SafetyMargin).Before(e.ExpiresAt)
+}
+
+// Dir returns the cache directory.
+func Dir() (string, error) {
+	dir, err := config.GetPangolinConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, cacheDirName), nil
+}
+
+// ExpiresAt returns when a signed certificate expires, preferring ValidBefore
+// and falling back to ExpiresInSeconds relative to signedAt. It returns the
+// zero time when neither is known.
+func ExpiresAt(d *api.SignSSHKeyData, signedAt time.Time) time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	if d.ValidBefore != "" {
+		if t, err := time.Parse(time.RFC3339, d.ValidBefore); err == nil {
+			return t
+		}
+	}
+	if d.ExpiresInSeconds > 0 {
+		return signedAt.Add(time.Duration(d.ExpiresInSeconds) * time.Second)
+	}
+	return time.Time{}
+}
+
+// Lookup returns a still-valid cached entry for key, or nil.
+func Lookup(key Key) (*Entry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entry, err := readEntry(filepath.Join(dir, key.fileName()))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !entry.Valid(time.Now()) {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Store writes entry to the cache under key. Entries without a known expiry
+// are not cached.
+func Store(key Key, entry *Entry) error {
+	if entry.ExpiresAt.IsZero() {
+		return nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create ssh certificate cache: %w", err)
+	}
+
+	entry.UserID = key.UserID
+	entry.OrgID = key.OrgID
+	entry.Resource = key.Resource
+	entry.Username = key.Username
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal ssh certificate cache entry: %w", err)
+	}
+
+	path := filepath.Join(dir, key.fileName())
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write ssh certificate cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write ssh certificate cache entry: %w", err)
+	}
+	entry.path = path
+	return nil
+}
+
+// List returns all cached entries, including expired ones, sorted by
+// resource and then expiry.
+func List() ([]*Entry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read ssh certificate cache: %w", err)
+	}
+
+	var entries []*Entry
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		entry, err := readEntry(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Resource != entries[j].Resource {
+			return entries[i].Resource < entries[j].Resource
+		}
+		return entries[i].ExpiresAt.Before(entries[j].ExpiresAt)
+	})
+	return entries, nil
+}
+
+// Purge removes cached entries for which match returns true (all entries
+// when match is nil) and returns how many were removed.
+func Purge(match func(*Entry) bool) (int, error) {
+	entries, err := List()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, e := range entries {
+		if match != nil && !match(e) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("remove %s: %w", e.path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func readEntry(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("parse ssh certificate cache entry: %w", err)
+	}
+	entry.path = path
+	return &entry, nil
+}
@@ -0,0 +1,325 @@
+// Package sshknownhosts keeps a per-organization OpenSSH known_hosts file
+// under the CLI config directory and verifies host keys against it with
+// trust-on-first-use pinning.
+package sshknownhosts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fosrl/cli/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const knownHostsDirName = "known_hosts.d"
+
+// Entry is one line of a known_hosts file.
+type Entry struct {
+	// Marker is "" for a pinned host key, "cert-authority" for a host CA,
+	// or "revoked".
+	Marker string
+	Hosts  []string
+	Key    ssh.PublicKey
+	Line   int
+}
+
+// Fingerprint returns the SHA256 fingerprint of the entry's key.
+func (e Entry) Fingerprint() string {
+	return ssh.FingerprintSHA256(e.Key)
+}
+
+// MismatchError is returned when a host presents a key that differs from
+// the one pinned for it.
+type MismatchError struct {
+	Host string
+	Path string
+	Got  ssh.PublicKey
+	Want []knownhosts.KnownKey
+}
+
+func (e *MismatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "host key verification failed for %s: the host key has changed\n", e.Host)
+	b.WriteString("This could mean someone is intercepting the connection, or that the host was reinstalled.\n")
+	for _, want := range e.Want {
+		fmt.Fprintf(&b, "  - expected %s %s (%s:%d)\n", want.Key.Type(), ssh.FingerprintSHA256(want.Key), want.Filename, want.Line)
+	}
+	fmt.Fprintf(&b, "  + received %s %s\n", e.Got.Type(), ssh.FingerprintSHA256(e.Got))
+	fmt.Fprintf(&b, "If the change is expected, run: pangolin ssh known-hosts remove %s", e.Host)
+	return b.String()
+}
+
+// Path returns the known_hosts file for orgID.
+func Path(orgID string) (string, error) {
+	if orgID == "" || strings.ContainsAny(orgID, `/\`) || orgID == "." || orgID == ".." {
+		return "", fmt.Errorf("invalid organization ID %q", orgID)
+	}
+	dir, err := config.GetPangolinConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, knownHostsDirName, orgID), nil
+}
+
+// Ensure creates the known_hosts file for orgID if it does not exist and
+// returns its path. OpenSSH is happy with an empty file.
+func Ensure(orgID string) (string, error) {
+	path, err := Path(orgID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("create known_hosts: %w", err)
+	}
+	f.Close()
+	return path, nil
+}
+
+// Callback returns a host key callback that verifies against the known_hosts
+// file at path. Unknown hosts are pinned on first use: plain host keys as a
+// regular entry, host certificates by pinning their signing CA as a
+// @cert-authority entry. A host that already has an entry must match it: a
+// changed key is a hard error (*MismatchError), as is a certificate from a
+// CA that is not recorded for the host.
+func Callback(path string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		check, err := knownhosts.New(path)
+		if err != nil {
+			return err
+		}
+		err = check(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var revoked *knownhosts.RevokedError
+		if errors.As(err, &revoked) {
+			return fmt.Errorf("host key for %s is revoked (%s:%d)", hostname, revoked.Revoked.Filename, revoked.Revoked.Line)
+		}
+
+		host := knownhosts.Normalize(hostname)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) > 0 {
+			return &MismatchError{Host: host, Path: path, Got: key, Want: keyErr.Want}
+		}
+
+		// Only a host with no entries at all is pinned on first use. Once a
+		// key or CA is recorded for it, anything else it presents is refused,
+		// so a certificate from another CA cannot replace a pinned key.
+		entries, readErr := Read(path)
+		if readErr != nil {
+			return readErr
+		}
+		if known := hostEntries(entries, host); len(known) > 0 {
+			if hasAuthority(known) {
+				return fmt.Errorf("host key for %s is not signed by a trusted CA: %w", hostname, err)
+			}
+			want := make([]knownhosts.KnownKey, 0, len(known))
+			for _, e := range known {
+				want = append(want, knownhosts.KnownKey{Key: e.Key, Filename: path, Line: e.Line})
+			}
+			return &MismatchError{Host: host, Path: path, Got: key, Want: want}
+		}
+
+		if cert, ok := key.(*ssh.Certificate); ok && cert.CertType == ssh.HostCert {
+			if err := appendLine(path, "@cert-authority "+knownhosts.Line([]string{hostname}, cert.SignatureKey)); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Trusting host CA %s for %s\n", ssh.FingerprintSHA256(cert.SignatureKey), host)
+			// Re-run the check so certificate validity and principals are
+			// still enforced against the newly pinned CA.
+			check, err = knownhosts.New(path)
+			if err != nil {
+				return err
+			}
+			return check(hostname, remote, key)
+		}
+
+		if keyErr == nil {
+			return err
+		}
+
+		if err := appendLine(path, knownhosts.Line([]string{hostname}, key)); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Pinned host key %s %s for %s\n", key.Type(), ssh.FingerprintSHA256(key), host)
+		return nil
+	}
+}
+
+// Read parses the known_hosts file at path. A missing file has no entries.
+func Read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		marker, hosts, key, _, _, err := ssh.ParseKnownHosts(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		entries = append(entries, Entry{Marker: marker, Hosts: hosts, Key: key, Line: i + 1})
+	}
+	return entries, nil
+}
+
+// Remove deletes every entry that lists host (in any normalized form) and
+// returns how many lines were removed.
+func Remove(path, host string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	target := knownhosts.Normalize(host)
+	var kept [][]byte
+	removed := 0
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && trimmed[0] != '#' {
+			if _, hosts, _, _, _, err := ssh.ParseKnownHosts(trimmed); err == nil && containsHost(hosts, target) {
+				removed++
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	out := bytes.Join(kept, []byte("\n"))
+	if len(out) > 0 {
+		out = append(out, '\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out, 0o600); err != nil {
+		return 0, fmt.Errorf("write known_hosts: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("write known_hosts: %w", err)
+	}
+	return removed, nil
+}
+
+// HasEntries reports whether any host key or CA is recorded for host.
+func HasEntries(path, host string) (bool, error) {
+	entries, err := Read(path)
+	if err != nil {
+		return false, err
+	}
+	return len(hostEntries(entries, knownhosts.Normalize(host))) > 0, nil
+}
+
+// Pin records key for host, replacing any existing entries for it.
+func Pin(path, host string, key ssh.PublicKey) error {
+	if _, err := Remove(path, host); err != nil {
+		return err
+	}
+	if cert, ok := key.(*ssh.Certificate); ok && cert.CertType == ssh.HostCert {
+		return appendLine(path, "@cert-authority "+knownhosts.Line([]string{host}, cert.SignatureKey))
+	}
+	return appendLine(path, knownhosts.Line([]string{host}, key))
+}
+
+func appendLine(path, line string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open known_hosts: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return fmt.Errorf("write known_hosts: %w", err)
+	}
+	return nil
+}
+
+// hostEntries returns the host keys and CAs recorded for host, including
+// those listed under a wildcard or hashed pattern.
+func hostEntries(entries []Entry, host string) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if e.Marker == "revoked" {
+			continue
+		}
+		if slices.ContainsFunc(e.Hosts, func(p string) bool { return matchHost(p, host) }) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// matchHost reports whether the known_hosts pattern p matches host. Negated
+// patterns are ignored.
+func matchHost(p, host string) bool {
+	if strings.HasPrefix(p, "!") {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(p, "|1|"); ok {
+		saltB64, hashB64, ok := strings.Cut(rest, "|")
+		if !ok {
+			return false
+		}
+		salt, err1 := base64.StdEncoding.DecodeString(saltB64)
+		hash, err2 := base64.StdEncoding.DecodeString(hashB64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+	if knownhosts.Normalize(p) == host {
+		return true
+	}
+	ok, _ := path.Match(p, host)
+	return ok
+}
+
+func hasAuthority(entries []Entry) bool {
+	for _, e := range entries {
+		if e.Marker == "cert-authority" {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHost(hosts []string, target string) bool {
+	for _, h := range hosts {
+		if knownhosts.Normalize(h) == target {
+			return true
+		}
+	}
+	return false
+}
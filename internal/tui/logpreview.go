@@ -23,6 +23,7 @@ type StatusFormatter func(isRunning bool, status *olm.StatusResponse) string
 // LogPreviewConfig configures the log preview TUI
 type LogPreviewConfig struct {
 	LogFile         string
+	SocketPath      string // Control socket of the client instance (default instance if empty)
 	Header          string
 	ExitCondition   ExitCondition
 	OnEarlyExit     func(client *olm.Client)                         // Called when user exits early (Ctrl+C)
@@ -48,7 +49,7 @@ type logPreviewModel struct {
 func NewLogPreview(config LogPreviewConfig) (completed bool, statusError *olm.StatusError, err error) {
 	model := &logPreviewModel{
 		config:    config,
-		olmClient: olm.NewClient(""),
+		olmClient: olm.NewClient(config.SocketPath),
 		logLines:  []string{},
 	}
 
@@ -106,6 +106,13 @@ func SwitchActiveClientOrg(orgID string) bool {
 		return false
 	}
 
+	// Another instance may already be connected to the target org; two
+	// tunnels to one org would conflict, so leave the default one as is.
+	if status, err := olm.ClientForOrg(orgID).GetStatus(); err == nil && status.OrgID == orgID {
+		logger.Info("Organization %s is already served by another running client; not switching the default client", orgID)
+		return false
+	}
+
 	// Client is running, try to switch org
 	_, err = client.SwitchOrg(orgID)
 	if err != nil {